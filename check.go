@@ -0,0 +1,256 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rakyll/statik/fs"
+	"github.com/rakyll/statik/gen"
+)
+
+// errStale is returned by check when the generated file is out of date.
+var errStale = errors.New("generated file is out of date; run statik again")
+
+// check generates the package in memory and compares it with the
+// existing generated file at dest, printing the differences.
+// It returns errStale if they differ. Nothing is written to disk.
+func check(dest string, src []byte) error {
+	old, err := ioutil.ReadFile(dest)
+	if os.IsNotExist(err) {
+		fmt.Printf("statik: %s does not exist\n", dest)
+		return errStale
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(old, src) {
+		return nil
+	}
+
+	oldCode, oldData, err := splitSource(old)
+	if err != nil {
+		return fmt.Errorf("%s: %s", dest, err)
+	}
+	newCode, newData, err := splitSource(src)
+	if err != nil {
+		return err
+	}
+	// Without -m, -reproducible or -mtime=git, modification times depend
+	// on the checkout and only the contents of the assets are compared.
+	withMtime := *flagNoMtime || *flagReproducible || *flagMtime == "git"
+	d, err := diffAssets(oldData, newData, withMtime)
+	if err != nil {
+		return fmt.Errorf("%s: %s", dest, err)
+	}
+	// The fingerprint changes with the modification times; the assets
+	// are compared instead.
+	codeChanged := stripFingerprint(oldCode) != stripFingerprint(newCode)
+	if !withMtime && d.empty() && !codeChanged {
+		return nil
+	}
+	fmt.Printf("statik: %s is out of date:\n", dest)
+	d.print()
+	if codeChanged {
+		fmt.Println("  generated code changed")
+	} else if d.empty() {
+		fmt.Println("  archive encoding changed")
+	}
+	return errStale
+}
+
+// splitSource returns the code of a generated file without its zip data
+// literal, and the unquoted zip data.
+func splitSource(src []byte) (code, data string, err error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return "", "", err
+	}
+	var lit *ast.BasicLit
+	ast.Inspect(f, func(n ast.Node) bool {
+		as, ok := n.(*ast.AssignStmt)
+		if !ok || lit != nil || len(as.Lhs) != 1 || len(as.Rhs) != 1 {
+			return lit == nil
+		}
+		if id, ok := as.Lhs[0].(*ast.Ident); ok && id.Name == "data" {
+			if bl, ok := as.Rhs[0].(*ast.BasicLit); ok && bl.Kind == token.STRING {
+				lit = bl
+			}
+		}
+		return lit == nil
+	})
+	if lit == nil {
+		return "", "", errors.New("no zip data found; not generated by statik?")
+	}
+	if data, err = strconv.Unquote(lit.Value); err != nil {
+		return "", "", err
+	}
+	start := fset.Position(lit.Pos()).Offset
+	end := start + len(lit.Value)
+	return string(src[:start]) + string(src[end:]), data, nil
+}
+
+// stripFingerprint removes the fingerprint line from the generated code.
+func stripFingerprint(code string) string {
+	i := strings.Index(code, "\n"+gen.FingerprintPrefix)
+	if i < 0 {
+		return code
+	}
+	end := strings.Index(code[i+1:], "\n")
+	if end < 0 {
+		return code[:i]
+	}
+	return code[:i] + code[i+1+end:]
+}
+
+// assetDiff lists the paths of the assets that differ between
+// two versions of a namespace.
+type assetDiff struct {
+	added, removed, changed []string
+}
+
+func (d *assetDiff) empty() bool {
+	return len(d.added)+len(d.removed)+len(d.changed) == 0
+}
+
+func (d *assetDiff) print() {
+	for _, p := range d.added {
+		fmt.Println("  + " + p)
+	}
+	for _, p := range d.removed {
+		fmt.Println("  - " + p)
+	}
+	for _, p := range d.changed {
+		fmt.Println("  ~ " + p)
+	}
+}
+
+// diffAssets compares the files of two zip data strings as they are
+// seen through statik/fs, including modification times if withMtime
+// is true.
+func diffAssets(oldData, newData string, withMtime bool) (*assetDiff, error) {
+	oldAssets, err := readAssets(oldData)
+	if err != nil {
+		return nil, err
+	}
+	newAssets, err := readAssets(newData)
+	if err != nil {
+		return nil, err
+	}
+	return compareAssets(oldAssets, newAssets, withMtime), nil
+}
+
+// compareAssets compares two sets of assets returned by readAssets.
+func compareAssets(oldAssets, newAssets map[string]asset, withMtime bool) *assetDiff {
+	d := &assetDiff{}
+	for name, n := range newAssets {
+		o, ok := oldAssets[name]
+		switch {
+		case !ok:
+			d.added = append(d.added, name)
+		case o.data != n.data || o.fi.Mode() != n.fi.Mode() || o.contentType != n.contentType:
+			d.changed = append(d.changed, name)
+		case withMtime && !o.fi.ModTime().Equal(n.fi.ModTime()):
+			d.changed = append(d.changed, name)
+		}
+	}
+	for name := range oldAssets {
+		if _, ok := newAssets[name]; !ok {
+			d.removed = append(d.removed, name)
+		}
+	}
+	sort.Strings(d.added)
+	sort.Strings(d.removed)
+	sort.Strings(d.changed)
+	return d
+}
+
+type asset struct {
+	fi          os.FileInfo
+	data        string
+	contentType string
+}
+
+// checkNamespace is the namespace zip data is registered with to be
+// read by readAssets. checkMu guards its registration.
+const checkNamespace = "statik check"
+
+var checkMu sync.Mutex
+
+// readAssets returns the files of the zip data, by path, and the
+// metadata entries holding header and redirect rules, by entry name.
+func readAssets(data string) (map[string]asset, error) {
+	checkMu.Lock()
+	fs.RegisterWithNamespace(checkNamespace, data)
+	hfs, err := fs.NewUncached(checkNamespace)
+	checkMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	assets := make(map[string]asset)
+	if _, err := hfs.Open("/"); err == os.ErrNotExist {
+		// There are no files at all.
+		return assets, nil
+	}
+	err = fs.Walk(hfs, "/", func(name string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(hfs, name)
+		if err != nil {
+			return err
+		}
+		assets[name] = asset{fi: fi, data: string(b), contentType: fs.ContentType(hfs, name)}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(strings.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, zf := range zr.File {
+		// Other metadata entries are derived from the files, or are
+		// part of the archive encoding.
+		switch zf.Name {
+		case fs.MetaHeaders, fs.MetaRedirects:
+			rc, err := zf.Open()
+			if err != nil {
+				return nil, err
+			}
+			b, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			assets[zf.Name] = asset{fi: zf.FileInfo(), data: string(b)}
+		}
+	}
+	return assets, nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// config describes the packages to generate in a single invocation.
+type config struct {
+	Targets []target `json:"targets"`
+}
+
+// target describes a package to generate. Each field corresponds to
+// a flag; fields left empty take the default value of their flag.
+// Relative paths are relative to the directory of the config file.
+type target struct {
+	Src       string   `json:"src"`
+	Dest      string   `json:"dest"`
+	Output    string   `json:"output"`
+	Package   string   `json:"package"`
+	Namespace string   `json:"namespace"`
+	Comment   string   `json:"comment"`
+	Include   []string `json:"include"`
+	Exclude   []string `json:"exclude"`
+	Tags      string   `json:"tags"`
+	Force     bool     `json:"force"`
+	NoMtime   bool     `json:"no_mtime"`
+	Consts    bool     `json:"consts"`
+
+	Reproducible bool `json:"reproducible"`
+
+	// Mtime is "file" (the default) or "git"; see -mtime.
+	Mtime string `json:"mtime"`
+
+	// MIMETypes maps extensions to content types; see -mime.
+	MIMETypes map[string]string `json:"mime_types"`
+
+	// MaxFileSize and MaxTotalSize are sizes such as "10MB".
+	MaxFileSize  string `json:"max_file_size"`
+	MaxTotalSize string `json:"max_total_size"`
+	WarnBudgets  bool   `json:"warn_budgets"`
+	Budgets      []struct {
+		Pattern string `json:"pattern"`
+		MaxSize string `json:"max_size"`
+	} `json:"budgets"`
+
+	// Compression is one of "deflate" (the default), "none", "dict"
+	// or "solid".
+	Compression string `json:"compression"`
+
+	Transforms []struct {
+		Pattern string `json:"pattern"`
+		Command string `json:"command"`
+	} `json:"transforms"`
+}
+
+// readConfig reads and validates the config file at filename.
+func readConfig(filename string) (*config, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	var c config
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	if len(c.Targets) == 0 {
+		return nil, fmt.Errorf("%s: no targets", filename)
+	}
+	dir := filepath.Dir(filename)
+	for i := range c.Targets {
+		t := &c.Targets[i]
+		switch t.Compression {
+		case "", "deflate", "none", "dict", "solid":
+		default:
+			return nil, fmt.Errorf("%s: target %d: unknown compression %q", filename, i, t.Compression)
+		}
+		if t.Src != "" && !filepath.IsAbs(t.Src) {
+			t.Src = filepath.Join(dir, t.Src)
+		}
+		if t.Dest != "" && !filepath.IsAbs(t.Dest) {
+			t.Dest = filepath.Join(dir, t.Dest)
+		}
+		if t.Output == stdoutOutput {
+			return nil, fmt.Errorf("%s: target %d: output cannot be the standard output", filename, i)
+		}
+		if t.Output != "" && !filepath.IsAbs(t.Output) {
+			t.Output = filepath.Join(dir, t.Output)
+		}
+	}
+	return &c, nil
+}
+
+// targetFlags are the names of the flags set by targets.
+var targetFlags = []string{
+	"src", "dest", "o", "p", "ns", "c", "include", "exclude", "tags",
+	"f", "m", "reproducible", "mtime", "Z", "dict", "solid", "consts",
+	"max-file-size", "max-total-size", "warn-budgets",
+}
+
+// flags returns the values of the flags the target sets.
+func (t *target) flags() map[string]string {
+	values := make(map[string]string)
+	set := func(name, value string) {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	set("src", t.Src)
+	set("dest", t.Dest)
+	set("o", t.Output)
+	set("p", t.Package)
+	set("ns", t.Namespace)
+	set("c", t.Comment)
+	set("include", strings.Join(t.Include, ","))
+	set("exclude", strings.Join(t.Exclude, ","))
+	set("tags", t.Tags)
+	if t.Force {
+		set("f", strconv.FormatBool(t.Force))
+	}
+	if t.NoMtime {
+		set("m", strconv.FormatBool(t.NoMtime))
+	}
+	set("mtime", t.Mtime)
+	if t.Reproducible {
+		set("reproducible", strconv.FormatBool(t.Reproducible))
+	}
+	if t.Consts {
+		set("consts", strconv.FormatBool(t.Consts))
+	}
+	set("max-file-size", t.MaxFileSize)
+	set("max-total-size", t.MaxTotalSize)
+	if t.WarnBudgets {
+		set("warn-budgets", strconv.FormatBool(t.WarnBudgets))
+	}
+	switch t.Compression {
+	case "none":
+		set("Z", "true")
+	case "dict":
+		set("dict", "true")
+	case "solid":
+		set("solid", "true")
+	}
+	return values
+}
+
+// explicitFlags returns the names of the flags given on the command
+// line. It must be called before runConfig, which sets the flags of
+// the targets.
+func explicitFlags() map[string]bool {
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	return explicit
+}
+
+// runConfig generates every target of the config file at filename.
+// Flags named in explicit, the ones given on the command line,
+// override the values of the targets.
+func runConfig(filename string, explicit map[string]bool) error {
+	c, err := readConfig(filename)
+	if err != nil {
+		return err
+	}
+	for i, t := range c.Targets {
+		// Start each target from the default values so that
+		// targets do not inherit the values of previous ones.
+		values := t.flags()
+		for _, name := range targetFlags {
+			if explicit[name] {
+				continue
+			}
+			value, ok := values[name]
+			if !ok {
+				value = flag.Lookup(name).DefValue
+			}
+			if err := flag.Set(name, value); err != nil {
+				return fmt.Errorf("target %d: -%s: %s", i, name, err)
+			}
+		}
+		if !explicit["transform"] {
+			flagTransforms = nil
+			for _, tr := range t.Transforms {
+				if err := flagTransforms.Set(tr.Pattern + "=" + tr.Command); err != nil {
+					return fmt.Errorf("target %d: %s", i, err)
+				}
+			}
+		}
+		if !explicit["mime"] {
+			flagMIME = nil
+			for ext, typ := range t.MIMETypes {
+				if err := flagMIME.Set(ext + "=" + typ); err != nil {
+					return fmt.Errorf("target %d: %s", i, err)
+				}
+			}
+		}
+		if !explicit["budget"] {
+			flagBudgets = nil
+			for _, b := range t.Budgets {
+				if err := flagBudgets.Set(b.Pattern + "=" + b.MaxSize); err != nil {
+					return fmt.Errorf("target %d: %s", i, err)
+				}
+			}
+		}
+		if err := run(); err != nil {
+			return fmt.Errorf("target %d: %s", i, err)
+		}
+	}
+	return nil
+}
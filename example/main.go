@@ -18,6 +18,6 @@ func main() {
 		log.Fatal(err)
 	}
 
-	http.Handle("/public/", http.StripPrefix("/public/", http.FileServer(statikFS)))
+	http.Handle("/public/", http.StripPrefix("/public/", fs.FileServer(statikFS)))
 	http.ListenAndServe(":8080", nil)
 }
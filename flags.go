@@ -0,0 +1,124 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rakyll/statik/gen"
+)
+
+// transformFlag collects the transforms given with repeated -transform flags.
+type transformFlag []gen.Transform
+
+func (f *transformFlag) String() string {
+	var s []string
+	for _, t := range *f {
+		s = append(s, t.Pattern+"="+t.Command)
+	}
+	return strings.Join(s, ",")
+}
+
+func (f *transformFlag) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("invalid transform %q; want pattern=command", value)
+	}
+	t := gen.Transform{Pattern: value[:i], Command: value[i+1:]}
+	if _, err := filepath.Match(t.Pattern, ""); err != nil {
+		return fmt.Errorf("invalid transform pattern %q: %s", t.Pattern, err)
+	}
+	*f = append(*f, t)
+	return nil
+}
+
+// sizeFlag is a size in bytes given with a unit, such as "10MB".
+type sizeFlag int64
+
+func (f *sizeFlag) String() string {
+	if *f == 0 {
+		return ""
+	}
+	return gen.FormatSize(int64(*f))
+}
+
+func (f *sizeFlag) Set(value string) error {
+	if value == "" {
+		*f = 0
+		return nil
+	}
+	n, err := gen.ParseSize(value)
+	if err != nil {
+		return err
+	}
+	*f = sizeFlag(n)
+	return nil
+}
+
+// budgetFlag collects the budgets given with repeated -budget flags.
+type budgetFlag []gen.Budget
+
+func (f *budgetFlag) String() string {
+	var s []string
+	for _, b := range *f {
+		s = append(s, b.Pattern+"="+gen.FormatSize(b.MaxSize))
+	}
+	return strings.Join(s, ",")
+}
+
+func (f *budgetFlag) Set(value string) error {
+	i := strings.LastIndex(value, "=")
+	if i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("invalid budget %q; want pattern=size", value)
+	}
+	b := gen.Budget{Pattern: value[:i]}
+	if _, err := filepath.Match(b.Pattern, ""); err != nil {
+		return fmt.Errorf("invalid budget pattern %q: %s", b.Pattern, err)
+	}
+	var err error
+	if b.MaxSize, err = gen.ParseSize(value[i+1:]); err != nil {
+		return err
+	}
+	*f = append(*f, b)
+	return nil
+}
+
+// mimeFlag collects the content types given with repeated -mime flags,
+// by lower-case extension.
+type mimeFlag map[string]string
+
+func (f *mimeFlag) String() string {
+	var s []string
+	for ext, t := range *f {
+		s = append(s, ext+"="+t)
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+func (f *mimeFlag) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 1 || value[0] != '.' || i == len(value)-1 {
+		return fmt.Errorf("invalid content type %q; want .ext=type", value)
+	}
+	if *f == nil {
+		*f = make(mimeFlag)
+	}
+	(*f)[strings.ToLower(value[:i])] = value[i+1:]
+	return nil
+}
@@ -14,7 +14,11 @@
 
 package fs
 
-import "testing"
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
 
 func BenchmarkOpen(b *testing.B) {
 	Register(mustZipTree("../testdata/index"))
@@ -47,3 +51,24 @@ func BenchmarkOpenDeep(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkNew(b *testing.B) {
+	files := make(map[string]string)
+	size := 0
+	for i := 0; i < 500; i++ {
+		var buf strings.Builder
+		for j := 0; buf.Len() < 32<<10; j++ {
+			fmt.Fprintf(&buf, "file %d, line %d: %x\n", i, j, j*i*2654435761)
+		}
+		files[fmt.Sprintf("dir%d/file%d.txt", i%10, i)] = buf.String()
+		size += buf.Len()
+	}
+	RegisterWithNamespace("bench new", mustZipFiles(files))
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewUncached("bench new"); err != nil {
+			b.Fatalf("NewUncached() = %v", err)
+		}
+	}
+}
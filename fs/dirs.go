@@ -0,0 +1,67 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// loadDirs adds the directories listed in dirsFile to fs.
+//
+// Each line of dirsFile describes a directory as
+//
+//	<name>\t<entry>\t<entry>...
+//
+// where name is the slash-separated path of the directory, "/" for
+// the root, and entries are the sorted names of its files and
+// directories, all quoted as Go string literals.
+func (fs *statikFS) loadDirs(dirsFile *zip.File) error {
+	b, err := unzip(dirsFile)
+	if err != nil {
+		return fmt.Errorf("statik/fs: error reading directory index: %s", err)
+	}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, len(b)+1)
+	for sc.Scan() {
+		fields := strings.Split(sc.Text(), "\t")
+		names := make([]string, len(fields))
+		for i, f := range fields {
+			if names[i], err = strconv.Unquote(f); err != nil {
+				return fmt.Errorf("statik/fs: invalid directory index line %q: %s", sc.Text(), err)
+			}
+		}
+		dn := names[0]
+		fs.files[dn] = file{FileInfo: dirInfo{dn}, fs: fs}
+		fs.dirs[dn] = names[1:]
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	// Every listed entry must be a file or a listed directory.
+	for dn, names := range fs.dirs {
+		for _, n := range names {
+			if _, ok := fs.files[path.Join(dn, n)]; !ok {
+				return fmt.Errorf("statik/fs: directory index lists unknown file %q", path.Join(dn, n))
+			}
+		}
+	}
+	return nil
+}
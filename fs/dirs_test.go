@@ -0,0 +1,78 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestLoadDirs(t *testing.T) {
+	files := map[string]string{
+		"a.txt":         "a",
+		"sub/b.txt":     "b",
+		"sub/deep/c.js": "c",
+	}
+	RegisterWithNamespace("dirs derived", mustZipFiles(files))
+	files[MetaDirs] = `"/"	"a.txt"	"sub"
+"/sub"	"b.txt"	"deep"
+"/sub/deep"	"c.js"
+`
+	RegisterWithNamespace("dirs indexed", mustZipFiles(files))
+
+	derived, err := NewUncached("dirs derived")
+	if err != nil {
+		t.Fatalf("NewUncached(dirs derived) = %v", err)
+	}
+	indexed, err := NewUncached("dirs indexed")
+	if err != nil {
+		t.Fatalf("NewUncached(dirs indexed) = %v", err)
+	}
+	for _, dir := range []string{"/", "/sub", "/sub/deep"} {
+		want := readdirNames(t, derived, dir)
+		if got := readdirNames(t, indexed, dir); !reflect.DeepEqual(got, want) {
+			t.Errorf("Readdir(%s) with an index = %v; want %v", dir, got, want)
+		}
+	}
+	if _, err := indexed.Open("/sub/deep/c.js"); err != nil {
+		t.Errorf("Open(/sub/deep/c.js) = %v", err)
+	}
+
+	RegisterWithNamespace("dirs unknown", mustZipFiles(map[string]string{
+		"a.txt":  "a",
+		MetaDirs: `"/"	"a.txt"	"b.txt"` + "\n",
+	}))
+	if _, err := NewUncached("dirs unknown"); err == nil {
+		t.Error("NewUncached() with an index listing an unknown file = nil; want an error")
+	}
+}
+
+func readdirNames(t *testing.T, hfs http.FileSystem, dir string) []string {
+	f, err := hfs.Open(dir)
+	if err != nil {
+		t.Fatalf("Open(%s) = %v", dir, err)
+	}
+	defer f.Close()
+	fis, err := f.Readdir(-1)
+	if err != nil {
+		t.Fatalf("Readdir(%s) = %v", dir, err)
+	}
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	return names
+}
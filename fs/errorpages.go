@@ -0,0 +1,82 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+)
+
+// errorPage returns the path and the file of the error page of the
+// given status for the URL path name: the <status>.html file of the
+// directory name, or of the nearest of its parents that has one.
+func (fs *statikFS) errorPage(name string, status int) (string, file, bool) {
+	page := strconv.Itoa(status) + ".html"
+	dir := name
+	if f, ok := fs.files[dir]; !ok || !f.IsDir() {
+		dir = path.Dir(dir)
+	}
+	for {
+		pn := path.Join(dir, page)
+		if f, ok := fs.files[pn]; ok && !f.IsDir() {
+			return pn, f, true
+		}
+		if dir == "/" || dir == "." {
+			return "", file{}, false
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// serveError responds to the request for the URL path name with the
+// error page of status, and reports whether there is one.
+func (fs *statikFS) serveError(w http.ResponseWriter, r *http.Request, name string, status int) bool {
+	pn, f, ok := fs.errorPage(name, status)
+	if !ok {
+		return false
+	}
+	w.Header().Set("Content-Type", serveType(pn, f))
+	writeFile(w, r, f, status)
+	return true
+}
+
+// errorPageWriter replaces the error responses written by
+// http.FileServer with the error pages of fs, if any.
+type errorPageWriter struct {
+	http.ResponseWriter
+	fs   *statikFS
+	r    *http.Request
+	name string
+
+	// served is set once an error page replaced the response, whose
+	// body is then discarded.
+	served bool
+}
+
+func (w *errorPageWriter) WriteHeader(status int) {
+	if status >= 400 && w.fs.serveError(w.ResponseWriter, w.r, w.name, status) {
+		w.served = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *errorPageWriter) Write(p []byte) (int, error) {
+	if w.served {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
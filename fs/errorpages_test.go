@@ -0,0 +1,96 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFileServerErrorPages(t *testing.T) {
+	RegisterWithNamespace("error pages", mustZipFiles(map[string]string{
+		"index.html":         "home",
+		"404.html":           "root not found",
+		"416.html":           "bad range",
+		"docs/404.html":      "docs not found",
+		"docs/guide/a.html":  "a",
+		"blog/post.html":     "post",
+		"shop/gone/404.html": "gone",
+		MetaRedirects:        "/old /missing.html 200\n",
+	}))
+	hfs, err := NewWithNamespace("error pages")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	h := FileServer(hfs)
+	for _, tt := range []struct {
+		method, path string
+		header       http.Header
+		code         int
+		body         string
+	}{
+		{path: "/missing", code: http.StatusNotFound, body: "root not found"},
+		{path: "/blog/missing.html", code: http.StatusNotFound, body: "root not found"},
+		{path: "/docs/missing", code: http.StatusNotFound, body: "docs not found"},
+		{path: "/docs/guide/missing/", code: http.StatusNotFound, body: "docs not found"},
+		{path: "/shop/gone/item", code: http.StatusNotFound, body: "gone"},
+		// Rewrites to missing files.
+		{path: "/old", code: http.StatusNotFound, body: "root not found"},
+		{method: "HEAD", path: "/missing", code: http.StatusNotFound},
+		{path: "/blog/post.html", header: http.Header{"Range": {"bytes=100-"}}, code: http.StatusRequestedRangeNotSatisfiable, body: "bad range"},
+		{path: "/blog/post.html", code: http.StatusOK, body: "post"},
+		{path: "/404.html", code: http.StatusOK, body: "root not found"},
+	} {
+		method := tt.method
+		if method == "" {
+			method = "GET"
+		}
+		req := httptest.NewRequest(method, tt.path, nil)
+		for k, v := range tt.header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("%s %s: status = %d; want %d", method, tt.path, rec.Code, tt.code)
+		}
+		if got := rec.Body.String(); got != tt.body {
+			t.Errorf("%s %s: body = %q; want %q", method, tt.path, got, tt.body)
+		}
+		if tt.code != http.StatusOK {
+			if got, want := rec.Header().Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+				t.Errorf("%s %s: Content-Type = %q; want %q", method, tt.path, got, want)
+			}
+			if got, want := rec.Header().Get("Content-Length"), strconv.Itoa(len(tt.body)); method == "GET" && got != want {
+				t.Errorf("%s %s: Content-Length = %q; want %q", method, tt.path, got, want)
+			}
+		}
+	}
+
+	// Without error pages, responses are those of http.FileServer.
+	RegisterWithNamespace("no error pages", mustZipFiles(map[string]string{"index.html": "home"}))
+	hfs, err = NewWithNamespace("no error pages")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	rec := httptest.NewRecorder()
+	FileServer(hfs).ServeHTTP(rec, httptest.NewRequest("GET", "/missing", nil))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "404 page not found") {
+		t.Errorf("GET /missing: %d %q; want the 404 of http.FileServer", rec.Code, rec.Body.String())
+	}
+}
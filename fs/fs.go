@@ -17,7 +17,7 @@ package fs
 
 import (
 	"archive/zip"
-	"bytes"
+	"compress/flate"
 	"errors"
 	"fmt"
 	"io"
@@ -26,27 +26,86 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
-var zipData = map[string]string{}
+var (
+	// mu guards zipData and cache.
+	mu      sync.Mutex
+	zipData = map[string]string{}
+	cache   = map[string]*cachedFS{}
+)
+
+// cachedFS is the file system of a namespace shared by the callers
+// of NewWithNamespace, built by the first one.
+type cachedFS struct {
+	once sync.Once
+	fs   http.FileSystem
+	err  error
+}
 
 // file holds unzipped read-only file contents and file metadata.
+// The contents of stored entries are slices of the registered data.
 type file struct {
 	os.FileInfo
-	data []byte
-	fs   *statikFS
+	data        string
+	contentType string
+	fs          *statikFS
 }
 
 type statikFS struct {
-	files map[string]file
-	dirs  map[string][]string
+	files     map[string]file
+	dirs      map[string][]string
+	headers   []HeaderRule
+	redirects []RedirectRule
+	// index lists the index file names directories resolve to,
+	// if set by WithIndex.
+	index []string
 }
 
 const defaultNamespace = "default"
 
+// Archive entries under MetaDir are reserved for metadata written by
+// the statik command and are not exposed as files.
+const (
+	// MetaDir is the archive directory that holds namespace metadata.
+	MetaDir = ".statik/"
+
+	// MetaDict is the archive entry that holds the preset dictionary
+	// used to compress entries with MethodDeflateDict.
+	MetaDict = MetaDir + "dict"
+
+	// MetaSolid is the archive entry that holds the contents of all
+	// files of a solid archive, concatenated and compressed as a
+	// single stream. MetaIndex lists the files within MetaSolid.
+	MetaSolid = MetaDir + "solid"
+	MetaIndex = MetaDir + "index"
+
+	// MetaTypes is the archive entry that holds the content type of
+	// every file.
+	MetaTypes = MetaDir + "types"
+
+	// MetaHeaders is the archive entry that holds the response header
+	// rules of the namespace; see ParseHeaders.
+	MetaHeaders = MetaDir + "headers"
+
+	// MetaRedirects is the archive entry that holds the redirect rules
+	// of the namespace; see ParseRedirects.
+	MetaRedirects = MetaDir + "redirects"
+
+	// MetaDirs is the archive entry that holds the sorted contents of
+	// every directory.
+	MetaDirs = MetaDir + "dirs"
+)
+
+// MethodDeflateDict is the zip compression method of entries that are
+// deflated with the preset dictionary stored in MetaDict.
+const MethodDeflateDict uint16 = 0x5344
+
 // IsDefaultNamespace returns true if the assetNamespace is
 // the default one
 func IsDefaultNamespace(assetNamespace string) bool {
@@ -60,40 +119,132 @@ func Register(data string) {
 }
 
 // RegisterWithNamespace registers zip contents data and set asset namespace,
-// later used to initialize the statik file system.
+// later used to initialize the statik file system. Registering data
+// again for a namespace replaces the file system returned by
+// NewWithNamespace for later calls.
 func RegisterWithNamespace(assetNamespace string, data string) {
+	mu.Lock()
+	defer mu.Unlock()
 	zipData[assetNamespace] = data
+	delete(cache, assetNamespace)
 }
 
-// New creates a new file system with the default registered zip contents data.
-// It unzips all files and stores them in an in-memory map.
+// New returns the file system of the default registered zip contents data.
+// See NewWithNamespace.
 func New() (http.FileSystem, error) {
 	return NewWithNamespace(defaultNamespace)
 }
 
-// NewWithNamespace creates a new file system with the registered zip contents data.
-// It unzips all files and stores them in an in-memory map.
+// NewWithNamespace returns the file system of the registered zip contents data.
+// The first call for a namespace unzips all files and stores them in an
+// in-memory map; later calls, including concurrent ones, return the same
+// read-only file system, safe for concurrent use.
 func NewWithNamespace(assetNamespace string) (http.FileSystem, error) {
+	mu.Lock()
 	asset, ok := zipData[assetNamespace]
+	c := cache[assetNamespace]
+	if ok && c == nil {
+		c = &cachedFS{}
+		cache[assetNamespace] = c
+	}
+	mu.Unlock()
 	if !ok {
 		return nil, errors.New("statik/fs: no zip data registered")
 	}
+	c.once.Do(func() {
+		c.fs, c.err = newFS(asset)
+	})
+	return c.fs, c.err
+}
+
+// NewUncached creates a new file system with the registered zip contents
+// data of the namespace, not shared with the callers of NewWithNamespace.
+// It unzips all files and stores them in an in-memory map.
+func NewUncached(assetNamespace string) (http.FileSystem, error) {
+	mu.Lock()
+	asset, ok := zipData[assetNamespace]
+	mu.Unlock()
+	if !ok {
+		return nil, errors.New("statik/fs: no zip data registered")
+	}
+	return newFS(asset)
+}
+
+// newFS creates a file system with the zip contents data asset.
+func newFS(asset string) (http.FileSystem, error) {
 	zipReader, err := zip.NewReader(strings.NewReader(asset), int64(len(asset)))
 	if err != nil {
 		return nil, err
 	}
+	meta := make(map[string]*zip.File)
+	for _, zipFile := range zipReader.File {
+		if strings.HasPrefix(zipFile.Name, MetaDir) {
+			meta[zipFile.Name] = zipFile
+		}
+	}
+	if dictFile, ok := meta[MetaDict]; ok {
+		if err := registerDict(zipReader, dictFile); err != nil {
+			return nil, err
+		}
+	}
 	files := make(map[string]file, len(zipReader.File))
 	dirs := make(map[string][]string)
 	fs := &statikFS{files: files, dirs: dirs}
+	if solidFile, ok := meta[MetaSolid]; ok {
+		if err := fs.loadSolid(meta[MetaIndex], solidFile); err != nil {
+			return nil, err
+		}
+	}
+	var zipFiles []*zip.File
 	for _, zipFile := range zipReader.File {
-		fi := zipFile.FileInfo()
-		f := file{FileInfo: fi, fs: fs}
-		f.data, err = unzip(zipFile)
+		if !strings.HasPrefix(zipFile.Name, MetaDir) {
+			zipFiles = append(zipFiles, zipFile)
+		}
+	}
+	data, err := unzipFiles(asset, zipFiles)
+	if err != nil {
+		return nil, err
+	}
+	for i, zipFile := range zipFiles {
+		files["/"+zipFile.Name] = file{FileInfo: zipFile.FileInfo(), data: data[i], fs: fs}
+	}
+	if typesFile, ok := meta[MetaTypes]; ok {
+		if err := fs.loadTypes(typesFile); err != nil {
+			return nil, err
+		}
+	}
+	if headersFile, ok := meta[MetaHeaders]; ok {
+		b, err := unzip(headersFile)
 		if err != nil {
-			return nil, fmt.Errorf("statik/fs: error unzipping file %q: %s", zipFile.Name, err)
+			return nil, fmt.Errorf("statik/fs: error reading headers: %s", err)
+		}
+		if fs.headers, err = ParseHeaders(b); err != nil {
+			return nil, fmt.Errorf("statik/fs: invalid headers: %s", err)
 		}
-		files["/"+zipFile.Name] = f
 	}
+	if redirectsFile, ok := meta[MetaRedirects]; ok {
+		b, err := unzip(redirectsFile)
+		if err != nil {
+			return nil, fmt.Errorf("statik/fs: error reading redirects: %s", err)
+		}
+		if fs.redirects, err = ParseRedirects(b); err != nil {
+			return nil, fmt.Errorf("statik/fs: invalid redirects: %s", err)
+		}
+	}
+	if dirsFile, ok := meta[MetaDirs]; ok {
+		if err := fs.loadDirs(dirsFile); err != nil {
+			return nil, err
+		}
+	} else {
+		fs.deriveDirs()
+	}
+	return fs, nil
+}
+
+// deriveDirs adds the directories of the files to fs, for archives
+// without a directory index.
+func (fs *statikFS) deriveDirs() {
+	files := fs.files
 	for fn := range files {
 		// go up directories recursively in order to care deep directory
 		for dn := path.Dir(fn); dn != fn; {
@@ -114,7 +265,19 @@ func NewWithNamespace(assetNamespace string) (http.FileSystem, error) {
 	for _, s := range fs.dirs {
 		sort.Strings(s)
 	}
-	return fs, nil
+}
+
+// registerDict registers a decompressor for MethodDeflateDict entries
+// using the preset dictionary stored in dictFile.
+func registerDict(zipReader *zip.Reader, dictFile *zip.File) error {
+	dict, err := unzip(dictFile)
+	if err != nil {
+		return fmt.Errorf("statik/fs: error reading dictionary: %s", err)
+	}
+	zipReader.RegisterDecompressor(MethodDeflateDict, func(r io.Reader) io.ReadCloser {
+		return flate.NewReaderDict(r, dict)
+	})
+	return nil
 }
 
 var _ = os.FileInfo(dirInfo{})
@@ -132,11 +295,11 @@ func (di dirInfo) Sys() interface{}   { return nil }
 
 // Open returns a file matching the given file name, or os.ErrNotExists if
 // no file matching the given file name is found in the archive.
-// If a directory is requested, Open returns the file named "index.html"
-// in the requested directory, if that file exists.
+// If the file system was returned by WithIndex and a directory is
+// requested, Open returns its index file, if the directory has one.
 func (fs *statikFS) Open(name string) (http.File, error) {
 	name = filepath.ToSlash(filepath.Clean(name))
-	if f, ok := fs.files[name]; ok {
+	if _, f, ok := fs.resolve(name); ok {
 		return newHTTPFile(f), nil
 	}
 	return nil, os.ErrNotExist
@@ -146,15 +309,16 @@ func newHTTPFile(file file) *httpFile {
 	if file.IsDir() {
 		return &httpFile{file: file, isDir: true}
 	}
-	return &httpFile{file: file, reader: bytes.NewReader(file.data)}
+	return &httpFile{file: file, reader: strings.NewReader(file.data)}
 }
 
 // httpFile represents an HTTP file and acts as a bridge
 // between file and http.File.
+// It also implements io.ReaderAt and io.WriterTo.
 type httpFile struct {
 	file
 
-	reader *bytes.Reader
+	reader *strings.Reader
 	isDir  bool
 	dirIdx int
 }
@@ -169,9 +333,41 @@ func (f *httpFile) Read(p []byte) (n int, err error) {
 
 // Seek seeks to the offset.
 func (f *httpFile) Seek(offset int64, whence int) (ret int64, err error) {
+	if f.isDir {
+		return 0, f.dirError("seek")
+	}
 	return f.reader.Seek(offset, whence)
 }
 
+// ReadAt reads len(p) bytes into p starting at offset off, as
+// io.ReaderAt does. It does not change the offset of Read and Seek,
+// and can be called concurrently.
+func (f *httpFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if f.isDir {
+		return 0, f.dirError("read")
+	}
+	return f.reader.ReadAt(p, off)
+}
+
+// WriteTo writes the rest of the file to w, as io.WriterTo does.
+func (f *httpFile) WriteTo(w io.Writer) (n int64, err error) {
+	if f.isDir {
+		return 0, f.dirError("read")
+	}
+	return f.reader.WriteTo(w)
+}
+
+// errIsDir is the error of reading a directory as a file.
+var errIsDir = errors.New("is a directory")
+
+func (f *httpFile) dirError(op string) error {
+	name := f.Name()
+	if di, ok := f.FileInfo.(dirInfo); ok {
+		name = di.name
+	}
+	return &os.PathError{Op: op, Path: name, Err: errIsDir}
+}
+
 // Stat stats the file.
 func (f *httpFile) Stat() (os.FileInfo, error) {
 	return f, nil
@@ -228,6 +424,76 @@ func (f *httpFile) Close() error {
 	return nil
 }
 
+// storedData returns the contents of the stored (uncompressed) entry zf
+// of the zip data asset as a slice of asset, without copying them.
+// Unlike unzip, it does not verify their checksum.
+func storedData(asset string, zf *zip.File) (string, error) {
+	off, err := zf.DataOffset()
+	if err != nil {
+		return "", err
+	}
+	size := int64(zf.UncompressedSize64)
+	if zf.CompressedSize64 != zf.UncompressedSize64 || off+size > int64(len(asset)) {
+		return "", zip.ErrFormat
+	}
+	return asset[off : off+size], nil
+}
+
+// unzipFiles returns the contents of the entries zfs of the zip data
+// asset. Compressed entries are inflated concurrently by up to
+// GOMAXPROCS workers. If several entries fail, the error names the
+// first one in archive order.
+func unzipFiles(asset string, zfs []*zip.File) ([]string, error) {
+	data := make([]string, len(zfs))
+	errs := make([]error, len(zfs))
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(zfs) {
+		workers = len(zfs)
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				data[i], errs[i] = unzipString(zfs[i])
+			}
+		}()
+	}
+	for i, zf := range zfs {
+		if zf.Method == zip.Store {
+			data[i], errs[i] = storedData(asset, zf)
+			continue
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("statik/fs: error unzipping file %q: %s", zfs[i].Name, err)
+		}
+	}
+	return data, nil
+}
+
+// unzipString returns the contents of zf like unzip, inflating them
+// into a string of their final size rather than copying them into one.
+func unzipString(zf *zip.File) (string, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	var b strings.Builder
+	b.Grow(int(zf.UncompressedSize64))
+	if _, err := io.Copy(&b, rc); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
 func unzip(zf *zip.File) ([]byte, error) {
 	rc, err := zf.Open()
 	if err != nil {
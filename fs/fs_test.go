@@ -17,12 +17,15 @@ import (
 	"archive/zip"
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
@@ -123,7 +126,7 @@ func TestOpen(t *testing.T) {
 				"/file.txt": {
 					data:    mustReadFile("../testdata/file/file.txt"),
 					isDir:   false,
-					modTime: fileTxtHeader.ModTime(),
+					modTime: fileTxtHeader.Modified,
 					mode:    fileTxtHeader.Mode(),
 					name:    fileTxtHeader.Name,
 					size:    int64(fileTxtHeader.UncompressedSize64),
@@ -137,7 +140,7 @@ func TestOpen(t *testing.T) {
 				"/pixel.gif": {
 					data:    mustReadFile("../testdata/image/pixel.gif"),
 					isDir:   false,
-					modTime: pixelGifHeader.ModTime(),
+					modTime: pixelGifHeader.Modified,
 					mode:    pixelGifHeader.Mode(),
 					name:    pixelGifHeader.Name,
 					size:    int64(pixelGifHeader.UncompressedSize64),
@@ -151,7 +154,7 @@ func TestOpen(t *testing.T) {
 				"/index.html": {
 					data:    mustReadFile("../testdata/index/index.html"),
 					isDir:   false,
-					modTime: indexHTMLHeader.ModTime(),
+					modTime: indexHTMLHeader.Modified,
 					mode:    indexHTMLHeader.Mode(),
 					name:    indexHTMLHeader.Name,
 					size:    int64(indexHTMLHeader.UncompressedSize64),
@@ -159,7 +162,7 @@ func TestOpen(t *testing.T) {
 				"/sub_dir/index.html": {
 					data:    mustReadFile("../testdata/index/sub_dir/index.html"),
 					isDir:   false,
-					modTime: subdirIndexHTMLHeader.ModTime(),
+					modTime: subdirIndexHTMLHeader.Modified,
 					mode:    subdirIndexHTMLHeader.Mode(),
 					name:    subdirIndexHTMLHeader.Name,
 					size:    int64(subdirIndexHTMLHeader.UncompressedSize64),
@@ -192,7 +195,7 @@ func TestOpen(t *testing.T) {
 				"/a": {
 					data:    mustReadFile("../testdata/deep/a"),
 					isDir:   false,
-					modTime: deepAHTMLHeader.ModTime(),
+					modTime: deepAHTMLHeader.Modified,
 					mode:    deepAHTMLHeader.Mode(),
 					name:    deepAHTMLHeader.Name,
 					size:    int64(deepAHTMLHeader.UncompressedSize64),
@@ -200,7 +203,7 @@ func TestOpen(t *testing.T) {
 				"/aa/bb/c": {
 					data:    mustReadFile("../testdata/deep/aa/bb/c"),
 					isDir:   false,
-					modTime: deepCHTMLHeader.ModTime(),
+					modTime: deepCHTMLHeader.Modified,
 					mode:    deepCHTMLHeader.Mode(),
 					name:    deepCHTMLHeader.Name,
 					size:    int64(deepCHTMLHeader.UncompressedSize64),
@@ -229,7 +232,7 @@ func TestOpen(t *testing.T) {
 				"/../file/../file/../file/.//file.txt": {
 					data:    mustReadFile("../testdata/file/file.txt"),
 					isDir:   false,
-					modTime: fileTxtHeader.ModTime(),
+					modTime: fileTxtHeader.Modified,
 					mode:    fileTxtHeader.Mode(),
 					name:    fileTxtHeader.Name,
 					size:    int64(fileTxtHeader.UncompressedSize64),
@@ -270,7 +273,7 @@ func TestOpen(t *testing.T) {
 				if got, want := stat.IsDir(), wantFile.isDir; got != want {
 					t.Errorf("IsDir(%v) = %t; want %t", name, got, want)
 				}
-				if got, want := stat.ModTime(), wantFile.modTime; got != want {
+				if got, want := stat.ModTime(), wantFile.modTime; !got.Equal(want) {
 					t.Errorf("ModTime(%v) = %v; want %v", name, got, want)
 				}
 				if got, want := stat.Mode(), wantFile.mode; got != want {
@@ -447,6 +450,170 @@ func TestOpen_Parallel(t *testing.T) {
 	wg.Wait()
 }
 
+func TestHTTPFile_ReadAtWriteTo(t *testing.T) {
+	RegisterWithNamespace("readat", mustZipFiles(map[string]string{
+		"dir/hello.txt": "Hello, statik!",
+	}))
+	fs, err := NewUncached("readat")
+	if err != nil {
+		t.Fatalf("NewUncached() = %v", err)
+	}
+	f, err := fs.Open("/dir/hello.txt")
+	if err != nil {
+		t.Fatalf("fs.Open(/dir/hello.txt) = %v", err)
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		t.Fatal("file does not implement io.ReaderAt")
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := make([]byte, 6)
+			if n, err := ra.ReadAt(p, 7); n != 6 || err != nil || string(p) != "statik" {
+				t.Errorf("ReadAt(p, 7) = %d, %v, %q; want 6, nil, %q", n, err, p[:n], "statik")
+			}
+		}()
+	}
+	wg.Wait()
+	p := make([]byte, 4)
+	if n, err := ra.ReadAt(p, 12); n != 2 || err != io.EOF {
+		t.Errorf("ReadAt(p, 12) = %d, %v; want 2, EOF", n, err)
+	}
+
+	// ReadAt does not move the offset of WriteTo.
+	if _, err := f.Seek(7, io.SeekStart); err != nil {
+		t.Fatalf("Seek(7) = %v", err)
+	}
+	var buf bytes.Buffer
+	if n, err := f.(io.WriterTo).WriteTo(&buf); n != 7 || err != nil || buf.String() != "statik!" {
+		t.Errorf("WriteTo() = %d, %v, %q; want 7, nil, %q", n, err, buf.String(), "statik!")
+	}
+
+	dir, err := fs.Open("/dir")
+	if err != nil {
+		t.Fatalf("fs.Open(/dir) = %v", err)
+	}
+	if _, err := dir.(io.ReaderAt).ReadAt(p, 0); err == nil {
+		t.Error("ReadAt() on a directory = nil; want an error")
+	}
+	if _, err := dir.(io.WriterTo).WriteTo(&buf); err == nil {
+		t.Error("WriteTo() on a directory = nil; want an error")
+	}
+	if _, err := dir.Seek(0, io.SeekStart); err == nil {
+		t.Error("Seek() on a directory = nil; want an error")
+	}
+}
+
+func TestNewWithNamespace_Cached(t *testing.T) {
+	RegisterWithNamespace("cached", mustZipTree("../testdata/file"))
+	var wg sync.WaitGroup
+	fss := make([]http.FileSystem, 10)
+	for i := range fss {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fs, err := NewWithNamespace("cached")
+			if err != nil {
+				t.Errorf("NewWithNamespace() = %v", err)
+			}
+			fss[i] = fs
+		}(i)
+	}
+	wg.Wait()
+	for _, fs := range fss[1:] {
+		if fs != fss[0] {
+			t.Fatalf("NewWithNamespace() returned different file systems for the same namespace")
+		}
+	}
+
+	uncached, err := NewUncached("cached")
+	if err != nil {
+		t.Fatalf("NewUncached() = %v", err)
+	}
+	if uncached == fss[0] {
+		t.Errorf("NewUncached() returned the shared file system")
+	}
+
+	RegisterWithNamespace("cached", mustZipTree("../testdata/deep"))
+	fs, err := NewWithNamespace("cached")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	if fs == fss[0] {
+		t.Fatalf("NewWithNamespace() returned the file system of data registered before")
+	}
+	if _, err := fs.Open("/aa/bb/c"); err != nil {
+		t.Errorf("Open(/aa/bb/c) = %v", err)
+	}
+
+	if _, err := NewUncached("not registered"); err == nil {
+		t.Errorf("NewUncached() of an unregistered namespace = nil error; want an error")
+	}
+}
+
+// Test that the contents of stored entries are not copied.
+func TestNew_Stored(t *testing.T) {
+	content := strings.Repeat("statik", 1<<20/6)
+	var out bytes.Buffer
+	w := zip.NewWriter(&out)
+	f, err := w.CreateHeader(&zip.FileHeader{Name: "big.txt", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(f, content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	RegisterWithNamespace("stored", out.String())
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	fs, err := NewWithNamespace("stored")
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > uint64(len(content))/2 {
+		t.Errorf("NewWithNamespace() allocated %d bytes for a stored file of %d bytes", alloc, len(content))
+	}
+	b, err := ReadFile(fs, "/big.txt")
+	if err != nil {
+		t.Fatalf("ReadFile() = %v", err)
+	}
+	if string(b) != content {
+		t.Errorf("ReadFile() returned %d bytes; want the %d stored bytes", len(b), len(content))
+	}
+}
+
+func TestNew_Compressed(t *testing.T) {
+	content := strings.Repeat("statik", 4<<20/6)
+	RegisterWithNamespace("compressed", mustZipFiles(map[string]string{"big.txt": content}))
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	fs, err := NewUncached("compressed")
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatalf("NewUncached() = %v", err)
+	}
+	// The contents are inflated once, into a string of their size.
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > uint64(len(content))*3/2 {
+		t.Errorf("NewUncached() allocated %d bytes for a compressed file of %d bytes", alloc, len(content))
+	}
+	b, err := ReadFile(fs, "/big.txt")
+	if err != nil {
+		t.Fatalf("ReadFile() = %v", err)
+	}
+	if string(b) != content {
+		t.Errorf("ReadFile() returned %d bytes; want the %d compressed bytes", len(b), len(content))
+	}
+}
+
 // mustZipTree walks on the source path and returns the zipped file contents
 // as a string. Panics on any errors.
 func mustZipTree(srcPath string) string {
@@ -511,5 +678,38 @@ func mustFileHeader(filename string) *zip.FileHeader {
 	if err != nil {
 		panic(err)
 	}
+	// Archives store modification times with a precision of one second.
+	header.Modified = header.Modified.Truncate(time.Second)
 	return header
 }
+
+func TestNew_UnzipError(t *testing.T) {
+	files := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf("file%02d.txt", i)] = strings.Repeat("statik ", 1000)
+	}
+	asset := []byte(mustZipFiles(files))
+	zr, err := zip.NewReader(bytes.NewReader(asset), int64(len(asset)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Corrupt the contents of a few entries, from the end of the archive,
+	// so that the later ones may fail first.
+	for _, i := range []int{17, 12, 5} {
+		off, err := zr.File[i].DataOffset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := int64(0); j < int64(zr.File[i].CompressedSize64); j++ {
+			asset[off+j] ^= 0xff
+		}
+	}
+	RegisterWithNamespace("unzip error", string(asset))
+	_, err = NewUncached("unzip error")
+	if err == nil {
+		t.Fatal("NewUncached() with corrupted entries = nil; want an error")
+	}
+	if want := `"file05.txt"`; !strings.Contains(err.Error(), want) {
+		t.Errorf("NewUncached() = %v; want an error naming %s", err, want)
+	}
+}
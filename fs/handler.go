@@ -0,0 +1,159 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// FileServer returns a handler that serves HTTP requests with the
+// contents of the file system hfs, like http.FileServer. If hfs is a
+// statik file system, responses use the content types recorded when it
+// was generated rather than the MIME tables of the host, carry the
+// headers of the matching rules of its _headers file, and requests are
+// redirected or rewritten by the rules of its _redirects file. Error
+// responses are served with the error page of the status, such as
+// 404.html, of the nearest directory that has one.
+func FileServer(hfs http.FileSystem) http.Handler {
+	return &fileHandler{fs: hfs, h: http.FileServer(hfs)}
+}
+
+type fileHandler struct {
+	fs http.FileSystem
+	h  http.Handler
+}
+
+func (h *fileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + r.URL.Path)
+	fs, ok := h.fs.(*statikFS)
+	if !ok {
+		h.h.ServeHTTP(w, r)
+		return
+	}
+	if rule, to := fs.redirect(name); rule != nil {
+		switch rule.Status {
+		case http.StatusMovedPermanently, http.StatusFound:
+			if r.URL.RawQuery != "" && !strings.Contains(to, "?") {
+				to += "?" + r.URL.RawQuery
+			}
+			fs.setHeader(w, name, "")
+			http.Redirect(w, r, to, rule.Status)
+		default:
+			fs.serveFile(w, r, name, to, rule.Status)
+		}
+		return
+	}
+	if f, ok := fs.files[name]; ok && f.IsDir() && fs.index != nil {
+		if _, _, ok := fs.indexFile(name); ok {
+			fs.serveIndex(w, r, name)
+			return
+		}
+	}
+	ct := fs.files[name].contentType
+	if ct == "" {
+		// http.FileServer serves the index.html file of directories.
+		_, idx, _ := fs.indexFile(name)
+		ct = idx.contentType
+	}
+	fs.setHeader(w, name, ct)
+	h.h.ServeHTTP(&errorPageWriter{ResponseWriter: w, fs: fs, r: r, name: name}, r)
+}
+
+// serveIndex responds to the request for the directory at the URL path
+// name with its index file, redirecting to the path with a trailing
+// slash first, like http.FileServer does for index.html files.
+func (fs *statikFS) serveIndex(w http.ResponseWriter, r *http.Request, name string) {
+	if upath := r.URL.Path; upath != "" && !strings.HasSuffix(upath, "/") {
+		to := path.Base(upath) + "/"
+		if r.URL.RawQuery != "" {
+			to += "?" + r.URL.RawQuery
+		}
+		fs.setHeader(w, name, "")
+		w.Header().Set("Location", to)
+		w.WriteHeader(http.StatusMovedPermanently)
+		return
+	}
+	fs.serveFile(w, r, name, name, http.StatusOK)
+}
+
+// setHeader sets the content type of the response, if not empty, and
+// the headers of the rules matching the URL path name, which override it.
+func (fs *statikFS) setHeader(w http.ResponseWriter, name, contentType string) {
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	for k, vs := range fs.header(name) {
+		w.Header()[k] = vs
+	}
+}
+
+// serveFile responds to the request for the URL path name with the
+// contents of the file at the URL path target, or the index file of
+// target if it is a directory, and the given status.
+func (fs *statikFS) serveFile(w http.ResponseWriter, r *http.Request, name, target string, status int) {
+	if u := strings.IndexAny(target, "?#"); u >= 0 {
+		target = target[:u]
+	}
+	target = path.Clean(target)
+	f, ok := fs.files[target]
+	if ok && f.IsDir() {
+		target, f, ok = fs.indexFile(target)
+	}
+	if !ok {
+		if !fs.serveError(w, r, name, http.StatusNotFound) {
+			http.NotFound(w, r)
+		}
+		return
+	}
+	fs.setHeader(w, name, serveType(target, f))
+	if status == http.StatusOK {
+		http.ServeContent(w, r, target, f.ModTime(), strings.NewReader(f.data))
+		return
+	}
+	writeFile(w, r, f, status)
+}
+
+// serveType returns the content type the file f at the URL path
+// name is served with: the recorded one, or else the one
+// http.FileServer would use.
+func serveType(name string, f file) string {
+	if f.contentType != "" {
+		return f.contentType
+	}
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		return ct
+	}
+	head := f.data
+	if len(head) > 512 {
+		// DetectContentType considers at most 512 bytes.
+		head = head[:512]
+	}
+	return http.DetectContentType([]byte(head))
+}
+
+// writeFile responds with the contents of f and the given status,
+// without the conditional and range requests of http.ServeContent.
+func writeFile(w http.ResponseWriter, r *http.Request, f file, status int) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(f.data)))
+	w.WriteHeader(status)
+	if r.Method != "HEAD" {
+		io.WriteString(w, f.data)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestFileServerContentType(t *testing.T) {
+	RegisterWithNamespace("types", mustZipFiles(map[string]string{
+		"index.html":       "<!doctype html>",
+		"app.wasm":         "\x00asm",
+		"sub/data":         "plain",
+		"sub/untyped.wasm": "\x00asm",
+		MetaTypes: `"index.html"	text/html; charset=utf-8
+"app.wasm"	application/wasm
+"sub/data"	application/x-custom
+`,
+	}))
+	hfs, err := NewWithNamespace("types")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	if got, want := ContentType(hfs, "/sub/../app.wasm"), "application/wasm"; got != want {
+		t.Errorf("ContentType(/sub/../app.wasm) = %q; want %q", got, want)
+	}
+
+	h := FileServer(hfs)
+	for _, tt := range []struct {
+		path, want string
+	}{
+		{"/", "text/html; charset=utf-8"},
+		{"/app.wasm", "application/wasm"},
+		{"/sub/data", "application/x-custom"},
+		// Files without a recorded type are served as by http.FileServer.
+		{"/sub/untyped.wasm", "application/wasm"},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d; want %d", tt.path, rec.Code, http.StatusOK)
+		}
+		if got := rec.Header().Get("Content-Type"); got != tt.want {
+			t.Errorf("GET %s: Content-Type = %q; want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// mustZipFiles returns zip contents holding the given entries, by name.
+// Panics on any errors.
+func mustZipFiles(files map[string]string) string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var out bytes.Buffer
+	w := zip.NewWriter(&out)
+	for _, name := range names {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			panic(err)
+		}
+		if _, err := f.Write([]byte(files[name])); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+	return out.String()
+}
+
+func TestFileServerHeaders(t *testing.T) {
+	RegisterWithNamespace("headers", mustZipFiles(map[string]string{
+		"index.html":      "<!doctype html>",
+		"assets/app.js":   "app()",
+		"blog/2020/a.txt": "a",
+		MetaTypes: `"index.html"	text/html; charset=utf-8
+"assets/app.js"	text/javascript; charset=utf-8
+"blog/2020/a.txt"	text/plain; charset=utf-8
+`,
+		MetaHeaders: `# Applies to every response.
+/*
+  X-Frame-Options: DENY
+  Cache-Control: no-cache
+
+/assets/*
+  Cache-Control: public, max-age=31536000
+
+/blog/:year/a.txt
+  Content-Type: text/markdown
+`,
+	}))
+	hfs, err := NewWithNamespace("headers")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	h := FileServer(hfs)
+	for _, tt := range []struct {
+		path   string
+		status int
+		header http.Header
+	}{
+		{"/", http.StatusOK, http.Header{
+			"X-Frame-Options": {"DENY"},
+			"Cache-Control":   {"no-cache"},
+			"Content-Type":    {"text/html; charset=utf-8"},
+		}},
+		{"/assets/app.js", http.StatusOK, http.Header{
+			"X-Frame-Options": {"DENY"},
+			"Cache-Control":   {"no-cache", "public, max-age=31536000"},
+		}},
+		{"/blog/2020/a.txt", http.StatusOK, http.Header{
+			"Content-Type": {"text/markdown"},
+		}},
+		{"/missing", http.StatusNotFound, http.Header{
+			"X-Frame-Options": {"DENY"},
+		}},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("GET %s: status = %d; want %d", tt.path, rec.Code, tt.status)
+		}
+		for k, want := range tt.header {
+			if got := rec.Header()[k]; !reflect.DeepEqual(got, want) {
+				t.Errorf("GET %s: %s = %q; want %q", tt.path, k, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HeaderRule sets response headers for the URL paths matching Path.
+type HeaderRule struct {
+	// Path is a URL path where segments starting with ":" match any
+	// single segment and a final "*" segment matches the remaining
+	// segments, such as "/blog/:year/*".
+	Path string
+
+	Header http.Header
+
+	pattern pathPattern
+}
+
+// ParseHeaders parses header rules in the format of Netlify _headers
+// files: each rule is a path on a line of its own followed by indented
+// "Name: value" lines. Blank lines and lines starting with "#" are
+// ignored.
+//
+//	/*
+//	  X-Frame-Options: DENY
+//	/assets/*
+//	  Cache-Control: public, max-age=31536000, immutable
+func ParseHeaders(b []byte) ([]HeaderRule, error) {
+	var rules []HeaderRule
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, len(b)+1)
+	ruleLine := 0 // line of the path of the last rule
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line == trimmed {
+			// Not indented: a new rule.
+			if len(rules) > 0 && len(rules[len(rules)-1].Header) == 0 {
+				return nil, fmt.Errorf("line %d: no headers for %s", ruleLine, rules[len(rules)-1].Path)
+			}
+			if strings.ContainsAny(line, " \t") {
+				return nil, fmt.Errorf("line %d: invalid path %q", n, line)
+			}
+			pat, err := compilePattern(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", n, err)
+			}
+			rules = append(rules, HeaderRule{Path: line, Header: make(http.Header), pattern: pat})
+			ruleLine = n
+			continue
+		}
+		if len(rules) == 0 {
+			return nil, fmt.Errorf("line %d: header without a path", n)
+		}
+		i := strings.Index(trimmed, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: want Name: value, got %q", n, trimmed)
+		}
+		name, value := trimmed[:i], strings.TrimSpace(trimmed[i+1:])
+		if err := validHeaderName(name); err != nil {
+			return nil, fmt.Errorf("line %d: %s", n, err)
+		}
+		rules[len(rules)-1].Header.Add(name, value)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(rules) > 0 && len(rules[len(rules)-1].Header) == 0 {
+		return nil, fmt.Errorf("no headers for %s", rules[len(rules)-1].Path)
+	}
+	return rules, nil
+}
+
+// validHeaderName returns an error if name is not a valid HTTP header name.
+func validHeaderName(name string) error {
+	if name == "" {
+		return errors.New("empty header name")
+	}
+	for _, c := range name {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	return nil
+}
+
+// header returns the headers of the rules matching the URL path name,
+// merged in order.
+func (fs *statikFS) header(name string) http.Header {
+	var h http.Header
+	for _, r := range fs.headers {
+		if _, ok := r.pattern.match(name); !ok {
+			continue
+		}
+		if h == nil {
+			h = make(http.Header)
+		}
+		for k, vs := range r.Header {
+			h[k] = append(h[k], vs...)
+		}
+	}
+	return h
+}
@@ -0,0 +1,83 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseHeaders(t *testing.T) {
+	rules, err := ParseHeaders([]byte("/*\n  X-Frame-Options: DENY\n\n# CORS\n/api/:version/*\n\tAccess-Control-Allow-Origin: *\n"))
+	if err != nil {
+		t.Fatalf("ParseHeaders() = %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("ParseHeaders() returned %d rules; want 2", len(rules))
+	}
+	if rules[1].Path != "/api/:version/*" || !reflect.DeepEqual(rules[1].Header, http.Header{"Access-Control-Allow-Origin": {"*"}}) {
+		t.Errorf("rules[1] = %+v", rules[1])
+	}
+
+	for _, s := range []string{
+		"  X-Frame-Options: DENY\n",
+		"/a\n",
+		"/a\n/b\n  X: y\n",
+		"a/b\n  X: y\n",
+		"/a b\n  X: y\n",
+		"/a/*/b\n  X: y\n",
+		"/a\n  X-Frame-Options DENY\n",
+		"/a\n  X Frame: DENY\n",
+	} {
+		if _, err := ParseHeaders([]byte(s)); err == nil {
+			t.Errorf("ParseHeaders(%q) = nil error; want an error", s)
+		}
+	}
+	// Blank and comment lines after a rule without headers.
+	_, err = ParseHeaders([]byte("/a\n\n# comment\n/b\n  X: y\n"))
+	if want := "line 1: no headers for /a"; err == nil || err.Error() != want {
+		t.Errorf("ParseHeaders() = %v; want %s", err, want)
+	}
+}
+
+func TestPathPattern(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, path string
+		params        map[string]string
+		ok            bool
+	}{
+		{"/", "/", nil, true},
+		{"/a", "/a", nil, true},
+		{"/a", "/b", nil, false},
+		{"/a", "/a/b", nil, false},
+		{"/*", "/", map[string]string{"splat": ""}, true},
+		{"/*", "/a/b", map[string]string{"splat": "a/b"}, true},
+		{"/a/*", "/a", map[string]string{"splat": ""}, true},
+		{"/a/*", "/b/c", nil, false},
+		{"/blog/:year/:slug", "/blog/2020/hello", map[string]string{"year": "2020", "slug": "hello"}, true},
+		{"/blog/:year/:slug", "/blog/2020", nil, false},
+		{"/blog/:year/*", "/blog/2020/a/b", map[string]string{"year": "2020", "splat": "a/b"}, true},
+	} {
+		pat, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q) = %v", tt.pattern, err)
+		}
+		params, ok := pat.match(tt.path)
+		if ok != tt.ok || !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("%q.match(%q) = %v, %t; want %v, %t", tt.pattern, tt.path, params, ok, tt.params, tt.ok)
+		}
+	}
+}
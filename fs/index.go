@@ -0,0 +1,91 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"errors"
+	"net/http"
+	"path"
+)
+
+// DefaultIndexNames are the index file names WithIndex uses when
+// none are given, in order of preference.
+var DefaultIndexNames = []string{"index.html", "index.htm", "README.md"}
+
+// WithIndex returns a copy of the statik file system hfs that resolves
+// directories to their index file: the first of names, or of
+// DefaultIndexNames if names is empty, that the directory contains.
+//
+// Opening a directory that has an index file returns the index file,
+// and its Stat describes the index file. FileServer serves it at the
+// path of the directory, with a trailing slash, and redirects requests
+// without one. Directories without an index file, and Walk, are not
+// affected. hfs itself is left unchanged.
+//
+// The returned file system must be served with FileServer, not with
+// http.FileServer: since its directories open as files, http.FileServer
+// redirects "/dir/" to "/dir" and serves the index file there, which
+// breaks relative links in the index file.
+func WithIndex(hfs http.FileSystem, names ...string) (http.FileSystem, error) {
+	fs, ok := hfs.(*statikFS)
+	if !ok {
+		return nil, errors.New("statik/fs: WithIndex requires a statik file system")
+	}
+	if len(names) == 0 {
+		names = DefaultIndexNames
+	}
+	for _, n := range names {
+		if n == "" || n != path.Base(n) {
+			return nil, errors.New("statik/fs: invalid index file name " + n)
+		}
+	}
+	c := *fs
+	c.index = append([]string(nil), names...)
+	return &c, nil
+}
+
+// indexNames returns the index file names of fs. Without index
+// resolution, these are the ones http.FileServer serves.
+func (fs *statikFS) indexNames() []string {
+	if fs.index == nil {
+		return []string{"index.html"}
+	}
+	return fs.index
+}
+
+// indexFile returns the path and the file of the index file of the
+// directory dir, if any.
+func (fs *statikFS) indexFile(dir string) (string, file, bool) {
+	for _, n := range fs.indexNames() {
+		name := path.Join(dir, n)
+		if f, ok := fs.files[name]; ok && !f.IsDir() {
+			return name, f, true
+		}
+	}
+	return "", file{}, false
+}
+
+// resolve returns the path and the file of fs served for name: the
+// index file of name if fs resolves indexes and name is a directory
+// that has one, the file at name otherwise.
+func (fs *statikFS) resolve(name string) (string, file, bool) {
+	f, ok := fs.files[name]
+	if ok && f.IsDir() && fs.index != nil {
+		if in, idx, ok := fs.indexFile(name); ok {
+			return in, idx, true
+		}
+	}
+	return name, f, ok
+}
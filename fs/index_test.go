@@ -0,0 +1,166 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func registerIndexTree() {
+	RegisterWithNamespace("index", mustZipFiles(map[string]string{
+		"index.html":             "root",
+		"docs/index.htm":         "docs",
+		"docs/README.md":         "readme",
+		"docs/api/README.md":     "api",
+		"docs/api/v1/index.html": "v1",
+		"empty/file.txt":         "file",
+		MetaTypes:                `"docs/api/README.md"	text/markdown; charset=utf-8` + "\n",
+	}))
+}
+
+func TestWithIndex_Open(t *testing.T) {
+	registerIndexTree()
+	plain, err := NewUncached("index")
+	if err != nil {
+		t.Fatalf("NewUncached() = %v", err)
+	}
+	hfs, err := WithIndex(plain)
+	if err != nil {
+		t.Fatalf("WithIndex() = %v", err)
+	}
+	for _, tt := range []struct {
+		name, want, wantName string
+	}{
+		{"/", "root", "index.html"},
+		{"/docs", "docs", "index.htm"},
+		{"/docs/", "docs", "index.htm"},
+		{"/docs/api", "api", "README.md"},
+		{"/docs/api/v1", "v1", "index.html"},
+		{"/docs/README.md", "readme", "README.md"},
+	} {
+		f, err := hfs.Open(tt.name)
+		if err != nil {
+			t.Errorf("Open(%s) = %v", tt.name, err)
+			continue
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			t.Errorf("Stat(%s) = %v", tt.name, err)
+			continue
+		}
+		if fi.IsDir() || fi.Name() != tt.wantName {
+			t.Errorf("Stat(%s) = %s (dir: %v); want the file %s", tt.name, fi.Name(), fi.IsDir(), tt.wantName)
+		}
+		if b, _ := ioutil.ReadAll(f); string(b) != tt.want {
+			t.Errorf("Open(%s) contents = %q; want %q", tt.name, b, tt.want)
+		}
+	}
+	// Directories without an index file are opened as directories.
+	if f, err := hfs.Open("/empty"); err != nil {
+		t.Errorf("Open(/empty) = %v", err)
+	} else if fi, _ := f.Stat(); !fi.IsDir() {
+		t.Errorf("Stat(/empty) is not a directory")
+	}
+
+	// The file system WithIndex was given does not resolve indexes.
+	if f, err := plain.Open("/docs"); err != nil {
+		t.Errorf("Open(/docs) = %v", err)
+	} else if fi, _ := f.Stat(); !fi.IsDir() {
+		t.Errorf("Stat(/docs) without index resolution is not a directory")
+	}
+
+	// Custom names, in their order.
+	hfs, err = WithIndex(plain, "README.md")
+	if err != nil {
+		t.Fatalf("WithIndex(README.md) = %v", err)
+	}
+	if b, err := ReadFile(hfs, "/docs"); err != nil || string(b) != "readme" {
+		t.Errorf("ReadFile(/docs) = %q, %v; want %q", b, err, "readme")
+	}
+
+	var dirs []string
+	err = Walk(hfs, "/", func(name string, fi os.FileInfo, err error) error {
+		if fi.IsDir() {
+			dirs = append(dirs, name)
+		}
+		return err
+	})
+	if err != nil || len(dirs) != 5 {
+		t.Errorf("Walk() visited directories %v, %v; want 5 directories", dirs, err)
+	}
+
+	if _, err := WithIndex(plain, "sub/index.html"); err == nil {
+		t.Error("WithIndex(sub/index.html) = nil error; want an error")
+	}
+	if _, err := WithIndex(http.Dir(".")); err == nil {
+		t.Error("WithIndex(http.Dir) = nil error; want an error")
+	}
+}
+
+func TestWithIndex_FileServer(t *testing.T) {
+	registerIndexTree()
+	plain, err := NewUncached("index")
+	if err != nil {
+		t.Fatalf("NewUncached() = %v", err)
+	}
+	hfs, err := WithIndex(plain)
+	if err != nil {
+		t.Fatalf("WithIndex() = %v", err)
+	}
+	h := FileServer(hfs)
+	for _, tt := range []struct {
+		path     string
+		code     int
+		location string
+		body     string
+	}{
+		{path: "/", code: http.StatusOK, body: "root"},
+		{path: "/docs/", code: http.StatusOK, body: "docs"},
+		{path: "/docs/api/", code: http.StatusOK, body: "api"},
+		{path: "/docs/api/v1/", code: http.StatusOK, body: "v1"},
+		{path: "/docs", code: http.StatusMovedPermanently, location: "docs/"},
+		{path: "/docs/api?x=1", code: http.StatusMovedPermanently, location: "api/?x=1"},
+		{path: "/docs/api/v1", code: http.StatusMovedPermanently, location: "v1/"},
+		{path: "/docs/README.md", code: http.StatusOK, body: "readme"},
+		{path: "/docs/api/v1/index.html", code: http.StatusMovedPermanently, location: "./"},
+		{path: "/empty", code: http.StatusMovedPermanently, location: "empty/"},
+		{path: "/missing/", code: http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("GET %s: status = %d; want %d", tt.path, rec.Code, tt.code)
+			continue
+		}
+		if got := rec.Header().Get("Location"); got != tt.location {
+			t.Errorf("GET %s: Location = %q; want %q", tt.path, got, tt.location)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("GET %s: body = %q; want %q", tt.path, rec.Body.String(), tt.body)
+		}
+	}
+	if got, want := ContentType(hfs, "/docs/api"), "text/markdown; charset=utf-8"; got != want {
+		t.Errorf("ContentType(/docs/api) = %q; want %q", got, want)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/docs/api/", nil))
+	if got, want := rec.Header().Get("Content-Type"), "text/markdown; charset=utf-8"; got != want {
+		t.Errorf("GET /docs/api/: Content-Type = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"errors"
+	"strings"
+)
+
+// pathPattern matches URL paths against the path patterns of _headers
+// and _redirects files. Segments starting with ":" are placeholders
+// matching any single segment, and a final "*" segment matches the
+// remaining segments, if any.
+type pathPattern struct {
+	segments []string
+	splat    bool
+}
+
+func compilePattern(p string) (pathPattern, error) {
+	if !strings.HasPrefix(p, "/") {
+		return pathPattern{}, errors.New("path must start with /")
+	}
+	var pat pathPattern
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	if len(segments) == 1 && segments[0] == "" {
+		segments = nil
+	}
+	for i, s := range segments {
+		switch {
+		case s == "*" && i == len(segments)-1:
+			pat.splat = true
+			continue
+		case strings.Contains(s, "*"):
+			return pathPattern{}, errors.New("* must be the last segment of a path")
+		case s == ":":
+			return pathPattern{}, errors.New("unnamed placeholder")
+		}
+		pat.segments = append(pat.segments, s)
+	}
+	return pat, nil
+}
+
+// match reports whether the cleaned URL path p matches the pattern,
+// and returns the values of the placeholders by name, and the path
+// matched by the splat as "splat".
+func (pat pathPattern) match(p string) (map[string]string, bool) {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	if len(segments) == 1 && segments[0] == "" {
+		segments = nil
+	}
+	if len(segments) < len(pat.segments) || (!pat.splat && len(segments) != len(pat.segments)) {
+		return nil, false
+	}
+	var params map[string]string
+	for i, s := range pat.segments {
+		if strings.HasPrefix(s, ":") {
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[s[1:]] = segments[i]
+		} else if s != segments[i] {
+			return nil, false
+		}
+	}
+	if pat.splat {
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params["splat"] = strings.Join(segments[len(pat.segments):], "/")
+	}
+	return params, true
+}
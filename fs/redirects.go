@@ -0,0 +1,163 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RedirectRule redirects or rewrites the requests for the URL paths
+// matching From.
+type RedirectRule struct {
+	// From is a URL path where segments starting with ":" match any
+	// single segment and a final "*" segment matches the remaining
+	// segments, such as "/blog/:year/*".
+	From string
+
+	// To is the URL path or absolute URL of the destination, where
+	// ":name" is replaced with the segment matched by the placeholder
+	// of From with that name and ":splat" with the segments matched
+	// by "*".
+	To string
+
+	// Status is http.StatusMovedPermanently or http.StatusFound to
+	// redirect, http.StatusOK to serve the contents of To instead,
+	// or http.StatusNotFound to serve them with a 404 status.
+	Status int
+
+	// Force applies the rule even if a file exists at the requested
+	// path; by default, files shadow rules.
+	Force bool
+
+	pattern pathPattern
+}
+
+// placeholderRe matches the placeholders of a redirect destination.
+var placeholderRe = regexp.MustCompile(`:[A-Za-z_][A-Za-z0-9_]*`)
+
+// ParseRedirects parses redirect rules in the format of Netlify
+// _redirects files: each line holds a path pattern, a destination and
+// optionally a status, 301 by default, followed by "!" to force the rule.
+// Blank lines and lines starting with "#" are ignored.
+//
+//	/home              /
+//	/blog/:year/:slug  /posts/:slug  302
+//	/app/*             /app/index.html  200
+//	/*                 /404.html  404
+func ParseRedirects(b []byte) ([]RedirectRule, error) {
+	var rules []RedirectRule
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, len(b)+1)
+	for n := 1; sc.Scan(); n++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		r, err := parseRedirect(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", n, err)
+		}
+		rules = append(rules, r)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func parseRedirect(fields []string) (RedirectRule, error) {
+	if len(fields) < 2 || len(fields) > 3 {
+		return RedirectRule{}, fmt.Errorf("want from, to and an optional status, got %d fields", len(fields))
+	}
+	r := RedirectRule{From: fields[0], To: fields[1], Status: http.StatusMovedPermanently}
+	var err error
+	if r.pattern, err = compilePattern(r.From); err != nil {
+		return RedirectRule{}, err
+	}
+	if len(fields) == 3 {
+		status := fields[2]
+		if strings.HasSuffix(status, "!") {
+			status, r.Force = status[:len(status)-1], true
+		}
+		if r.Status, err = strconv.Atoi(status); err != nil {
+			return RedirectRule{}, fmt.Errorf("invalid status %q", fields[2])
+		}
+	}
+	external := strings.HasPrefix(r.To, "http://") || strings.HasPrefix(r.To, "https://")
+	switch r.Status {
+	case http.StatusMovedPermanently, http.StatusFound:
+		if !external && !strings.HasPrefix(r.To, "/") {
+			return RedirectRule{}, fmt.Errorf("destination %q must be a path or an absolute URL", r.To)
+		}
+	case http.StatusOK, http.StatusNotFound:
+		if !strings.HasPrefix(r.To, "/") {
+			return RedirectRule{}, fmt.Errorf("destination %q of a %d rule must be a path", r.To, r.Status)
+		}
+	default:
+		return RedirectRule{}, fmt.Errorf("unsupported status %d; want 301, 302, 200 or 404", r.Status)
+	}
+	for _, p := range placeholderRe.FindAllString(r.To, -1) {
+		if !r.hasPlaceholder(p[1:]) {
+			return RedirectRule{}, fmt.Errorf("placeholder %s of the destination is not in %s", p, r.From)
+		}
+	}
+	return r, nil
+}
+
+func (r *RedirectRule) hasPlaceholder(name string) bool {
+	if name == "splat" {
+		return r.pattern.splat
+	}
+	for _, s := range r.pattern.segments {
+		if s == ":"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// redirect returns the first rule matching the URL path name and its
+// destination, or nil if there is none. Rules that are not forced only
+// apply if there is no file to serve at name.
+func (fs *statikFS) redirect(name string) (*RedirectRule, string) {
+	for i := range fs.redirects {
+		r := &fs.redirects[i]
+		params, ok := r.pattern.match(name)
+		if !ok || (!r.Force && fs.exists(name)) {
+			continue
+		}
+		to := placeholderRe.ReplaceAllStringFunc(r.To, func(p string) string {
+			return params[p[1:]]
+		})
+		return r, to
+	}
+	return nil, ""
+}
+
+// exists reports whether the URL path name is served as a file,
+// or as the index file of a directory.
+func (fs *statikFS) exists(name string) bool {
+	if f, ok := fs.files[name]; ok && !f.IsDir() {
+		return true
+	}
+	_, _, ok := fs.indexFile(strings.TrimSuffix(name, "/"))
+	return ok
+}
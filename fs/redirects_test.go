@@ -0,0 +1,115 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseRedirects(t *testing.T) {
+	rules, err := ParseRedirects([]byte(`# Moved pages.
+/home  /
+/blog/:year/:slug  /posts/:slug  302
+/app/*  /app/index.html  200!
+`))
+	if err != nil {
+		t.Fatalf("ParseRedirects() = %v", err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("ParseRedirects() returned %d rules; want 3", len(rules))
+	}
+	for i, want := range []struct {
+		status int
+		force  bool
+	}{
+		{http.StatusMovedPermanently, false},
+		{http.StatusFound, false},
+		{http.StatusOK, true},
+	} {
+		if rules[i].Status != want.status || rules[i].Force != want.force {
+			t.Errorf("rules[%d] = %d %t; want %d %t", i, rules[i].Status, rules[i].Force, want.status, want.force)
+		}
+	}
+
+	for _, s := range []string{
+		"/a\n",
+		"/a /b 301 extra\n",
+		"a /b\n",
+		"/a b\n",
+		"/a /b 307\n",
+		"/a /b ok\n",
+		"/a https://example.com/ 200\n",
+		"/a/:x /b/:y\n",
+		"/a /b/:splat\n",
+		"/a/*/b /c\n",
+	} {
+		if _, err := ParseRedirects([]byte(s)); err == nil {
+			t.Errorf("ParseRedirects(%q) = nil error; want an error", s)
+		}
+	}
+}
+
+func TestFileServerRedirects(t *testing.T) {
+	RegisterWithNamespace("redirects", mustZipFiles(map[string]string{
+		"index.html":     "home",
+		"404.html":       "not found",
+		"app/index.html": "app",
+		"old.html":       "still here",
+		"shadowed.html":  "shadowed",
+		MetaRedirects: `/home  /
+/blog/:year/:slug  /posts/:year-:slug  302
+/docs/*  https://docs.example.com/:splat
+/old.html  /new.html
+/shadowed.html  /index.html  301!
+/app/*  /app/  200
+/*  /404.html  404
+`,
+	}))
+	hfs, err := NewWithNamespace("redirects")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	h := FileServer(hfs)
+	for _, tt := range []struct {
+		path     string
+		status   int
+		location string
+		body     string
+	}{
+		{"/home?ref=a", http.StatusMovedPermanently, "/?ref=a", ""},
+		{"/blog/2020/hello", http.StatusFound, "/posts/2020-hello", ""},
+		{"/docs/a/b", http.StatusMovedPermanently, "https://docs.example.com/a/b", ""},
+		// Files shadow rules unless they are forced.
+		{"/old.html", http.StatusOK, "", "still here"},
+		{"/shadowed.html", http.StatusMovedPermanently, "/index.html", ""},
+		{"/app/settings/1", http.StatusOK, "", "app"},
+		{"/app/", http.StatusOK, "", "app"},
+		{"/missing", http.StatusNotFound, "", "not found"},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("GET %s: status = %d; want %d", tt.path, rec.Code, tt.status)
+		}
+		if got := rec.Header().Get("Location"); got != tt.location {
+			t.Errorf("GET %s: Location = %q; want %q", tt.path, got, tt.location)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("GET %s: body = %q; want %q", tt.path, rec.Body.String(), tt.body)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadSolid adds the files of a solid archive to fs.
+//
+// Each line of the index describes a file as
+//
+//	<offset> <size> <mode> <mtime> <name>
+//
+// where offset and size locate the file contents in the decompressed
+// solid stream, mode is the decimal os.FileMode, mtime is in seconds
+// since the Unix epoch and name is the slash-separated path relative
+// to the root, quoted as a Go string literal.
+func (fs *statikFS) loadSolid(indexFile, solidFile *zip.File) error {
+	if indexFile == nil {
+		return errors.New("statik/fs: solid archive has no index")
+	}
+	index, err := unzip(indexFile)
+	if err != nil {
+		return fmt.Errorf("statik/fs: error reading index: %s", err)
+	}
+	// Files are slices of the solid stream.
+	solid, err := unzipString(solidFile)
+	if err != nil {
+		return fmt.Errorf("statik/fs: error reading solid archive: %s", err)
+	}
+	sc := bufio.NewScanner(bytes.NewReader(index))
+	sc.Buffer(nil, len(index)+1)
+	for sc.Scan() {
+		name, fi, off, err := parseIndexLine(sc.Text())
+		if err != nil {
+			return fmt.Errorf("statik/fs: invalid index line %q: %s", sc.Text(), err)
+		}
+		if off+fi.size > int64(len(solid)) {
+			return fmt.Errorf("statik/fs: file %q is out of the solid archive bounds", name)
+		}
+		fs.files["/"+name] = file{FileInfo: fi, data: solid[off : off+fi.size], fs: fs}
+	}
+	return sc.Err()
+}
+
+func parseIndexLine(line string) (name string, fi fileInfo, off int64, err error) {
+	fields := strings.SplitN(line, " ", 5)
+	if len(fields) != 5 {
+		return "", fi, 0, errors.New("missing fields")
+	}
+	var nums [4]int64
+	for i := range nums {
+		if nums[i], err = strconv.ParseInt(fields[i], 10, 64); err != nil {
+			return "", fi, 0, err
+		}
+		if nums[i] < 0 {
+			return "", fi, 0, errors.New("negative value")
+		}
+	}
+	if name, err = strconv.Unquote(fields[4]); err != nil {
+		return "", fi, 0, err
+	}
+	fi = fileInfo{
+		name:    path.Base(name),
+		size:    nums[1],
+		mode:    os.FileMode(nums[2]),
+		modTime: time.Unix(nums[3], 0).UTC(),
+	}
+	return name, fi, nums[0], nil
+}
+
+var _ = os.FileInfo(fileInfo{})
+
+// fileInfo describes a file that is not stored as a zip entry.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return false }
+func (fi fileInfo) Sys() interface{}   { return nil }
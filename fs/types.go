@@ -0,0 +1,75 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// loadTypes records the content types listed in typesFile.
+//
+// Each line of typesFile describes a file as
+//
+//	<name>\t<content type>
+//
+// where name is the slash-separated path relative to the root, quoted
+// as a Go string literal.
+func (fs *statikFS) loadTypes(typesFile *zip.File) error {
+	types, err := unzip(typesFile)
+	if err != nil {
+		return fmt.Errorf("statik/fs: error reading content types: %s", err)
+	}
+	sc := bufio.NewScanner(bytes.NewReader(types))
+	sc.Buffer(nil, len(types)+1)
+	for sc.Scan() {
+		line := sc.Text()
+		i := strings.LastIndex(line, "\t")
+		if i < 0 {
+			return fmt.Errorf("statik/fs: invalid content type line %q", line)
+		}
+		name, err := strconv.Unquote(line[:i])
+		if err != nil {
+			return fmt.Errorf("statik/fs: invalid content type line %q: %s", line, err)
+		}
+		f, ok := fs.files["/"+name]
+		if !ok {
+			return fmt.Errorf("statik/fs: content type of unknown file %q", name)
+		}
+		f.contentType = line[i+1:]
+		fs.files["/"+name] = f
+	}
+	return sc.Err()
+}
+
+// ContentType returns the content type recorded for the named file
+// of hfs when it was generated, or an empty string if hfs is not a
+// statik file system or has no content type for the file. Like Open,
+// it resolves directories to their index file if hfs was returned by
+// WithIndex.
+func ContentType(hfs http.FileSystem, name string) string {
+	fs, ok := hfs.(*statikFS)
+	if !ok {
+		return ""
+	}
+	_, f, _ := fs.resolve(filepath.ToSlash(filepath.Clean(name)))
+	return f.contentType
+}
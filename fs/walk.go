@@ -28,6 +28,12 @@ import (
 //
 // As with filepath.Walk, if the walkFn returns filepath.SkipDir, then the directory is skipped.
 func Walk(hfs http.FileSystem, root string, walkFn filepath.WalkFunc) error {
+	if fs, ok := hfs.(*statikFS); ok && fs.index != nil {
+		// Walk directories rather than their index files.
+		c := *fs
+		c.index = nil
+		hfs = &c
+	}
 	dh, err := hfs.Open(root)
 	if err != nil {
 		return err
@@ -72,8 +78,14 @@ func ReadFile(hfs http.FileSystem, name string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer fh.Close()
+	if f, ok := fh.(*httpFile); ok && !f.isDir {
+		// The contents of statik files are strings, which cannot be
+		// shared as a mutable slice: make a single copy of the string
+		// rather than growing a buffer.
+		return []byte(f.data), nil
+	}
 	var buf bytes.Buffer
 	_, err = io.Copy(&buf, fh)
-	fh.Close()
 	return buf.Bytes(), err
 }
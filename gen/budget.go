@@ -0,0 +1,119 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"fmt"
+	spath "path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Budget limits the size of the files whose name matches Pattern.
+type Budget struct {
+	// Pattern is a wildcard matched against file names, like
+	// the patterns of transforms.
+	Pattern string
+
+	// MaxSize is the maximum size of each matching file, in bytes.
+	// A MaxSize of 0 forbids matching files altogether.
+	MaxSize int64
+}
+
+// BudgetError reports the files exceeding their size budget,
+// and the total size if it exceeds Options.MaxTotalSize.
+type BudgetError []string
+
+func (e BudgetError) Error() string {
+	return "size budget exceeded:\n  " + strings.Join(e, "\n  ")
+}
+
+// checkBudgets returns the entries exceeding the size budgets of opts.
+// Sizes are those of the embedded contents, after transforms.
+func checkBudgets(opts *Options, entries []entry) BudgetError {
+	var errs BudgetError
+	var total int64
+	for _, e := range entries {
+		size := int64(len(e.data))
+		total += size
+		if opts.MaxFileSize > 0 && size > opts.MaxFileSize {
+			errs = append(errs, fmt.Sprintf("/%s: %s exceeds the maximum file size of %s",
+				e.header.Name, FormatSize(size), FormatSize(opts.MaxFileSize)))
+		}
+		name := spath.Base(e.header.Name)
+		for _, b := range opts.Budgets {
+			if ok, _ := filepath.Match(b.Pattern, name); !ok {
+				continue
+			}
+			// A budget of 0 forbids matching files, even empty ones.
+			switch {
+			case b.MaxSize == 0:
+				errs = append(errs, fmt.Sprintf("/%s: files matching %q are not allowed", e.header.Name, b.Pattern))
+			case size > b.MaxSize:
+				errs = append(errs, fmt.Sprintf("/%s: %s exceeds the budget of %s for %q",
+					e.header.Name, FormatSize(size), FormatSize(b.MaxSize), b.Pattern))
+			}
+		}
+	}
+	if opts.MaxTotalSize > 0 && total > opts.MaxTotalSize {
+		errs = append(errs, fmt.Sprintf("total size of %s exceeds the maximum of %s",
+			FormatSize(total), FormatSize(opts.MaxTotalSize)))
+	}
+	return errs
+}
+
+var sizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size in bytes with an optional unit, one of
+// B, K or KB, M or MB and G or GB, in powers of 1024. Units are
+// case-insensitive; "1.5MB" is 1572864 bytes.
+func ParseSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v, unit = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.size
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(f * float64(unit)), nil
+}
+
+// FormatSize formats a size in bytes with the largest unit
+// understood by ParseSize that keeps it at least 1.
+func FormatSize(n int64) string {
+	for _, u := range sizeUnits[:3] {
+		if n >= u.size {
+			return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/float64(u.size)), ".0") + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10) + "B"
+}
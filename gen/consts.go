@@ -0,0 +1,66 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"strconv"
+)
+
+// pathConst is a generated constant holding the path of an embedded file.
+type pathConst struct {
+	name string
+	path string
+}
+
+// pathConsts returns a constant for each of the paths, named after the
+// path with toSymbolSafe and prefix. Names colliding with previous ones
+// or with reserved get a numeric suffix.
+func pathConsts(prefix string, paths []string, reserved ...string) []pathConst {
+	used := make(map[string]bool)
+	for _, r := range reserved {
+		used[r] = true
+	}
+	consts := make([]pathConst, 0, len(paths))
+	for _, p := range paths {
+		base := prefix + toSymbolSafe(p)
+		if !ast.IsExported(base) {
+			base = "File" + base
+		}
+		name := base
+		for i := 2; used[name]; i++ {
+			name = base + strconv.Itoa(i)
+		}
+		used[name] = true
+		consts = append(consts, pathConst{name: name, path: p})
+	}
+	return consts
+}
+
+// writePathConsts writes the constants for the paths of the embedded
+// files, and a slice listing all of them named filesVar.
+func writePathConsts(w *bytes.Buffer, filesVar string, consts []pathConst) {
+	fmt.Fprint(w, "\n// Paths of the embedded files.\nconst (\n")
+	for _, c := range consts {
+		fmt.Fprintf(w, "\t%s = %q\n", c.name, c.path)
+	}
+	fmt.Fprintf(w, ")\n\n// %s lists the paths of all embedded files.\nvar %s = []string{\n", filesVar, filesVar)
+	for _, c := range consts {
+		fmt.Fprintf(w, "\t%s,\n", c.name)
+	}
+	fmt.Fprint(w, "}\n")
+}
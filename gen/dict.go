@@ -0,0 +1,204 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"bytes"
+	"compress/flate"
+	"sort"
+)
+
+const (
+	// maxDictSize is the largest useful preset dictionary; deflate
+	// can only refer back 32KB.
+	maxDictSize = 32 << 10
+
+	// dictGramSize is the length of the substrings counted while
+	// training a dictionary, dictSegmentSize the length of the
+	// pieces of content the dictionary is made of.
+	dictGramSize    = 8
+	dictSegmentSize = 64
+
+	// dictSampleSize is the number of bytes sampled from the
+	// entries while training a dictionary.
+	dictSampleSize = 1 << 20
+)
+
+// selectDict trains a preset dictionary from the entries and returns it
+// if compressing with it makes the entries smaller, or nil otherwise.
+func selectDict(entries []entry) ([]byte, DictStats, error) {
+	var stats DictStats
+	dict := trainDict(entries)
+	if len(dict) == 0 {
+		return nil, stats, nil
+	}
+	for _, e := range entries {
+		n, err := deflatedSize(e.data, nil)
+		if err != nil {
+			return nil, stats, err
+		}
+		stats.Plain += n
+		if n, err = deflatedSize(e.data, dict); err != nil {
+			return nil, stats, err
+		}
+		stats.WithDict += n
+	}
+	// The dictionary is stored once in the archive.
+	stats.WithDict += len(dict)
+	if stats.WithDict >= stats.Plain {
+		return nil, stats, nil
+	}
+	stats.Size = len(dict)
+	return dict, stats, nil
+}
+
+// segment is a piece of sampled content that may become part of
+// the dictionary.
+type segment struct {
+	data  []byte
+	score int
+}
+
+// trainDict builds a dictionary out of the segments of the entries that
+// cover the substrings shared by the largest number of entries. Once a
+// segment is picked, its substrings no longer count towards the score
+// of other segments, which keeps the dictionary free of duplicates.
+// The best segments are placed at the end, closest to the compressed data.
+func trainDict(entries []entry) []byte {
+	samples := sampleEntries(entries)
+
+	// Count the number of samples each substring appears in.
+	counts := make(map[uint64]int)
+	seen := make(map[uint64]bool)
+	for _, sample := range samples {
+		for k := range seen {
+			delete(seen, k)
+		}
+		for i := 0; i+dictGramSize <= len(sample); i++ {
+			g := gramHash(sample[i : i+dictGramSize])
+			if !seen[g] {
+				seen[g] = true
+				counts[g]++
+			}
+		}
+	}
+	// Substrings found in a single sample do not help others.
+	for g, n := range counts {
+		if n < 2 {
+			delete(counts, g)
+		}
+	}
+
+	score := func(data []byte) int {
+		n := 0
+		for i := 0; i+dictGramSize <= len(data); i++ {
+			n += counts[gramHash(data[i:i+dictGramSize])]
+		}
+		return n
+	}
+	var segments []segment
+	for _, sample := range samples {
+		for i := 0; i < len(sample); i += dictSegmentSize / 2 {
+			end := i + dictSegmentSize
+			if end > len(sample) {
+				end = len(sample)
+			}
+			if s := score(sample[i:end]); s > 0 {
+				segments = append(segments, segment{data: sample[i:end], score: s})
+			}
+		}
+	}
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].score > segments[j].score
+	})
+
+	var picked [][]byte
+	size := 0
+	for _, s := range segments {
+		if size+len(s.data) > maxDictSize {
+			break
+		}
+		// Rescore as previously picked segments may cover this one.
+		if score(s.data) < s.score/2 {
+			continue
+		}
+		for i := 0; i+dictGramSize <= len(s.data); i++ {
+			delete(counts, gramHash(s.data[i:i+dictGramSize]))
+		}
+		picked = append(picked, s.data)
+		size += len(s.data)
+	}
+
+	dict := make([]byte, 0, size)
+	for i := len(picked) - 1; i >= 0; i-- {
+		dict = append(dict, picked[i]...)
+	}
+	return dict
+}
+
+// sampleEntries returns up to dictSampleSize bytes of content,
+// spread over the entries.
+func sampleEntries(entries []entry) [][]byte {
+	if len(entries) == 0 {
+		return nil
+	}
+	perEntry := dictSampleSize / len(entries)
+	if perEntry < 512 {
+		perEntry = 512
+	}
+	if perEntry > 4<<10 {
+		perEntry = 4 << 10
+	}
+	stride := len(entries) * perEntry / dictSampleSize
+	if stride < 1 {
+		stride = 1
+	}
+	var samples [][]byte
+	for i := 0; i < len(entries); i += stride {
+		b := entries[i].data
+		if len(b) > perEntry {
+			b = b[:perEntry]
+		}
+		samples = append(samples, b)
+	}
+	return samples
+}
+
+// gramHash returns the FNV-1a hash of b.
+func gramHash(b []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return h
+}
+
+// deflatedSize returns the size of b compressed with the optional
+// preset dictionary dict.
+func deflatedSize(b, dict []byte) (int, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriterDict(&buf, flate.DefaultCompression, dict)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return 0, err
+	}
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+	return buf.Len(), nil
+}
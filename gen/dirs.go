@@ -0,0 +1,62 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"archive/zip"
+	"bytes"
+	spath "path"
+	"sort"
+	"strconv"
+
+	"github.com/rakyll/statik/fs"
+)
+
+// writeDirs writes the sorted contents of every directory of the
+// entries into w, so that statik/fs does not derive them at startup.
+// See the statik/fs package for the format.
+func writeDirs(w *zip.Writer, entries []entry) error {
+	dirs := make(map[string]map[string]bool)
+	for _, e := range entries {
+		for name := "/" + e.header.Name; name != "/"; name = spath.Dir(name) {
+			dn := spath.Dir(name)
+			if dirs[dn] == nil {
+				dirs[dn] = make(map[string]bool)
+			}
+			dirs[dn][spath.Base(name)] = true
+		}
+	}
+	var names []string
+	for dn := range dirs {
+		names = append(names, dn)
+	}
+	sort.Strings(names)
+	var index bytes.Buffer
+	for _, dn := range names {
+		var children []string
+		for c := range dirs[dn] {
+			children = append(children, c)
+		}
+		sort.Strings(children)
+		index.WriteString(strconv.Quote(dn))
+		for _, c := range children {
+			index.WriteString("\t" + strconv.Quote(c))
+		}
+		index.WriteString("\n")
+	}
+	h := &zip.FileHeader{Name: fs.MetaDirs, Method: zip.Deflate}
+	h.SetModTime(mtimeDate)
+	return writeEntry(w, entry{header: h, data: index.Bytes()})
+}
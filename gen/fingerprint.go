@@ -0,0 +1,138 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fingerprintVersion is part of every fingerprint and must be changed
+// whenever statik generates different code for the same inputs.
+const fingerprintVersion = 4
+
+// FingerprintPrefix starts the line of the generated code that holds
+// the fingerprint of its inputs.
+const FingerprintPrefix = "//statik:fingerprint "
+
+// fingerprint hashes the inputs of the generated code: the options
+// and the path, mode, contents and, unless NoMtime or Reproducible is
+// set, modification time of every embedded file.
+type fingerprint struct {
+	h hash.Hash
+}
+
+func newFingerprint(opts *Options) *fingerprint {
+	fp := &fingerprint{h: sha256.New()}
+	fmt.Fprintf(fp.h, "statik %d\n", fingerprintVersion)
+	// Every option changing the generated code must be listed here.
+	fmt.Fprintf(fp.h, "include=%q\nexclude=%q\n", opts.Include, opts.Exclude)
+	fmt.Fprintf(fp.h, "package=%q\nnamespace=%q\ncomment=%q\ntags=%q\n",
+		opts.Package, opts.Namespace, opts.Comment, opts.Tags)
+	fmt.Fprintf(fp.h, "nomtime=%t\nnocompress=%t\ndict=%t\nsolid=%t\nconsts=%t\n",
+		opts.NoMtime, opts.NoCompress, opts.Dict, opts.Solid, opts.Consts)
+	if opts.Reproducible || !opts.SourceDate.IsZero() || opts.GitMtime {
+		fmt.Fprintf(fp.h, "reproducible=%t\nsourcedate=%d\ngitmtime=%t\n",
+			opts.Reproducible, opts.SourceDate.Unix(), opts.GitMtime)
+	}
+	for _, t := range opts.Transforms {
+		fmt.Fprintf(fp.h, "transform=%q=%q\n", t.Pattern, t.Command)
+	}
+	var exts []string
+	for ext := range opts.MIMETypes {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		fmt.Fprintf(fp.h, "mimetype=%q=%q\n", ext, opts.MIMETypes[ext])
+	}
+	// Budgets do not change the generated code, but changing them
+	// must check the files again.
+	if opts.MaxFileSize > 0 || opts.MaxTotalSize > 0 || len(opts.Budgets) > 0 {
+		fmt.Fprintf(fp.h, "maxfilesize=%d\nmaxtotalsize=%d\nbudgets=%v\nwarnbudgets=%t\n",
+			opts.MaxFileSize, opts.MaxTotalSize, opts.Budgets, opts.WarnBudgets)
+	}
+	return fp
+}
+
+// add adds a file to the fingerprint, before it is transformed.
+func (fp *fingerprint) add(opts *Options, relPath string, fi os.FileInfo, b []byte) {
+	mode := fi.Mode()
+	if opts.Reproducible {
+		mode = reproducibleMode(mode)
+	}
+	fmt.Fprintf(fp.h, "%q %d %d", filepath.ToSlash(relPath), mode, len(b))
+	if !opts.noMtime() {
+		fmt.Fprintf(fp.h, " %d", fi.ModTime().Unix())
+	}
+	sum := sha256.Sum256(b)
+	fmt.Fprintf(fp.h, " %x\n", sum)
+}
+
+// addMeta adds a metadata entry read from the source directory
+// to the fingerprint.
+func (fp *fingerprint) addMeta(e entry) {
+	sum := sha256.Sum256(e.data)
+	fmt.Fprintf(fp.h, "meta %q %x\n", e.header.Name, sum)
+}
+
+func (fp *fingerprint) String() string {
+	return "sha256:" + hex.EncodeToString(fp.h.Sum(nil))
+}
+
+// Fingerprint returns the fingerprint of the code Generate would
+// generate with opts, without generating it. The fingerprint is
+// recorded in the generated code; see ReadFingerprint.
+func Fingerprint(ctx context.Context, opts Options) (string, error) {
+	opts.setDefaults()
+	fp := newFingerprint(&opts)
+	err := walkSource(ctx, &opts, func(relPath string, fi os.FileInfo, b []byte) error {
+		fp.add(&opts, relPath, fi, b)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	rules, err := readRules(&opts)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range rules {
+		fp.addMeta(e)
+	}
+	return fp.String(), nil
+}
+
+// ReadFingerprint returns the fingerprint recorded in the header
+// of the generated code read from r, or an empty string if there is none.
+func ReadFingerprint(r io.Reader) string {
+	sc := bufio.NewScanner(r)
+	// The fingerprint is on the line following the generated code notice.
+	for i := 0; i < 2 && sc.Scan(); i++ {
+		if line := sc.Text(); strings.HasPrefix(line, FingerprintPrefix) {
+			return strings.TrimPrefix(line, FingerprintPrefix)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,642 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gen generates code that registers a directory and its
+// contents as zip data for the statik file system.
+// It is the library behind the statik command.
+package gen
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	spath "path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rakyll/statik/fs"
+)
+
+// Options configures the code generated by Generate.
+type Options struct {
+	// Src is the source directory of the assets.
+	Src string
+
+	// Include lists wildcards filtering the files to include,
+	// all files by default. Exclude lists wildcards filtering
+	// the files and directories to exclude.
+	Include []string
+	Exclude []string
+
+	// Package is the name of the generated package, "statik" by default.
+	Package string
+
+	// Namespace is the namespace where assets will exist,
+	// the default namespace by default.
+	Namespace string
+
+	// Comment is the godoc of the generated package.
+	Comment string
+
+	// Tags are the build tags of the generated package.
+	Tags string
+
+	// NoMtime ignores modification times for deterministic output.
+	NoMtime bool
+
+	// Reproducible makes the generated code depend only on the paths
+	// and contents of the files and on the options, whatever the system
+	// and file system it is generated on: modification times are ignored
+	// like with NoMtime, modes are normalized to 0644, or 0755 for
+	// executable files, and no zip extra fields are recorded.
+	Reproducible bool
+
+	// SourceDate is the modification time of every file with NoMtime
+	// or Reproducible, 2000-01-01 UTC if zero. The command sets it from
+	// the SOURCE_DATE_EPOCH environment variable.
+	SourceDate time.Time
+
+	// GitMtime sets the modification time of every file to the time
+	// of the last commit changing it in the git repository containing
+	// Src, or to SourceDate if it was never committed. Uncommitted
+	// changes do not change the time of a file.
+	GitMtime bool
+
+	// NoCompress stores files without compression.
+	NoCompress bool
+
+	// Dict compresses files with a preset dictionary selected from
+	// the assets, if that makes the output smaller.
+	Dict bool
+
+	// Solid compresses all files as a single stream.
+	Solid bool
+
+	// Consts generates a constant for the path of every file,
+	// and a Files slice listing them.
+	Consts bool
+
+	// Transforms are applied to the contents of the matching files,
+	// in order. Their results are cached in TransformCache, unless
+	// it is empty.
+	Transforms     []Transform
+	TransformCache string
+
+	// MIMETypes maps file extensions, such as ".wasm", to the content
+	// type recorded for the files with that extension, overriding the
+	// built-in table. Files with an extension in neither get the content
+	// type sniffed from their contents.
+	MIMETypes map[string]string
+
+	// MaxFileSize and MaxTotalSize limit the size of every file and
+	// the total size of the files, if positive. Budgets limit the size
+	// of the matching files. Generate fails with a BudgetError listing
+	// the files exceeding a limit, unless WarnBudgets is set, in which
+	// case they are reported in Result.OverBudget.
+	MaxFileSize  int64
+	MaxTotalSize int64
+	Budgets      []Budget
+	WarnBudgets  bool
+
+	// Output receives the generated code.
+	Output io.Writer
+}
+
+// Result describes the code generated by Generate.
+type Result struct {
+	// Fingerprint identifies the inputs of the generated code.
+	// See Fingerprint.
+	Fingerprint string
+
+	// Paths lists the paths of the embedded files.
+	Paths []string
+
+	// Dict reports the effect of the preset dictionary,
+	// if Options.Dict is set.
+	Dict *DictStats
+
+	// Files reports the size of the archive entries, in archive order.
+	Files []FileStats
+
+	// ZipSize is the size of the zip data, and LiteralSize the size of
+	// the string literal holding it in the generated code.
+	ZipSize, LiteralSize int
+
+	// OverBudget lists the files exceeding their size budget,
+	// if Options.WarnBudgets is set.
+	OverBudget BudgetError
+}
+
+// FileStats reports the size of an archive entry.
+type FileStats struct {
+	// Path is the path of an embedded file, or the name of an archive
+	// entry under fs.MetaDir holding metadata.
+	Path string
+
+	// Size is the size of the contents, after transforms.
+	Size int64
+
+	// Compressed is the size of the contents in the archive. It is 0
+	// for files of solid archives, which are compressed together in
+	// the fs.MetaSolid entry.
+	Compressed int64
+
+	// Method is "store", "deflate", "deflate+dict" or "solid".
+	Method string
+}
+
+// DictStats reports the effect of a preset dictionary.
+type DictStats struct {
+	// Size is the size of the dictionary, or 0 if none was used
+	// because it did not make the output smaller.
+	Size int
+
+	// Plain is the compressed size of the files without a dictionary,
+	// WithDict the compressed size with it, dictionary included.
+	Plain, WithDict int
+}
+
+// mtimeDate holds the arbitrary mtime that we assign to files when
+// Options.NoMtime is set, unless Options.SourceDate is.
+var mtimeDate = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// noMtime reports whether the modification times of the files are ignored.
+// Times taken from git are reproducible and kept.
+func (opts *Options) noMtime() bool {
+	return opts.NoMtime || (opts.Reproducible && !opts.GitMtime)
+}
+
+// modTime returns the modification time of the files if noMtime is true.
+func (opts *Options) modTime() time.Time {
+	if opts.SourceDate.IsZero() {
+		return mtimeDate
+	}
+	return opts.SourceDate.UTC()
+}
+
+// reproducibleMode returns the mode recorded for a file of the given mode
+// with Options.Reproducible.
+func reproducibleMode(mode os.FileMode) os.FileMode {
+	if mode&0111 != 0 {
+		return 0755
+	}
+	return 0644
+}
+
+func (opts *Options) setDefaults() {
+	if opts.Package == "" {
+		opts.Package = "statik"
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = "default"
+	}
+}
+
+func (opts *Options) validate() error {
+	switch {
+	case opts.Output == nil:
+		return errors.New("statik/gen: no output")
+	case opts.Solid && opts.NoCompress:
+		return errors.New("solid archives cannot be used without compression")
+	case opts.Solid && opts.Dict:
+		return errors.New("solid archives cannot be used with a preset dictionary")
+	case opts.Dict && opts.NoCompress:
+		return errors.New("a preset dictionary cannot be used without compression")
+	case opts.GitMtime && opts.NoMtime:
+		return errors.New("modification times cannot be both ignored and taken from git")
+	}
+	return nil
+}
+
+// entry is a source file collected to be written into the archive.
+type entry struct {
+	header *zip.FileHeader
+	data   []byte
+}
+
+// Generate walks on the source directory and writes source code
+// to opts.Output that contains the directory's contents as zip contents.
+// The generated code registers the zip contents data to be read
+// by the statik/fs HTTP file system.
+func Generate(ctx context.Context, opts Options) (Result, error) {
+	opts.setDefaults()
+	if err := opts.validate(); err != nil {
+		return Result{}, err
+	}
+
+	var (
+		res       Result
+		entries   []entry
+		transErrs TransformError
+		err       error
+	)
+	fp := newFingerprint(&opts)
+	if err := walkSource(ctx, &opts, func(relPath string, fi os.FileInfo, b []byte) error {
+		fp.add(&opts, relPath, fi, b)
+		if len(opts.Transforms) > 0 {
+			tb, err := applyTransforms(ctx, opts.Transforms, opts.TransformCache, relPath, b)
+			if err != nil {
+				// Keep going to report all failing files at once.
+				transErrs = append(transErrs, err.Error())
+				return nil
+			}
+			b = tb
+		}
+
+		fHeader, err := zip.FileInfoHeader(fi)
+		if err != nil {
+			return err
+		}
+		fHeader.UncompressedSize64 = uint64(len(b))
+		if opts.noMtime() {
+			// Always use the same modification time so that
+			// the output is deterministic with respect to the file contents.
+			fHeader.SetModTime(opts.modTime())
+		}
+		if opts.Reproducible {
+			// Permissions depend on the umask and the file system,
+			// e.g. on Windows all files are 0666 or 0444.
+			fHeader.SetMode(reproducibleMode(fi.Mode()))
+			fHeader.Extra = nil
+		}
+		fHeader.Name = filepath.ToSlash(relPath)
+		if !opts.NoCompress {
+			fHeader.Method = zip.Deflate
+		}
+		entries = append(entries, entry{header: fHeader, data: b})
+		return nil
+	}); err != nil {
+		return res, err
+	}
+	if len(transErrs) > 0 {
+		return res, transErrs
+	}
+	rules, err := readRules(&opts)
+	if err != nil {
+		return res, err
+	}
+	for _, e := range rules {
+		fp.addMeta(e)
+	}
+	if errs := checkBudgets(&opts, entries); len(errs) > 0 {
+		if !opts.WarnBudgets {
+			return res, errs
+		}
+		res.OverBudget = errs
+	}
+	res.Fingerprint = fp.String()
+	res.Paths = make([]string, len(entries))
+	for i, e := range entries {
+		res.Paths[i] = "/" + e.header.Name
+	}
+
+	var dict []byte
+	if opts.Dict {
+		var (
+			stats DictStats
+			err   error
+		)
+		if dict, stats, err = selectDict(entries); err != nil {
+			return res, err
+		}
+		res.Dict = &stats
+	}
+
+	var buffer bytes.Buffer
+	w := zip.NewWriter(&buffer)
+	if err := writeTypes(w, entries, opts.MIMETypes); err != nil {
+		return res, err
+	}
+	if err := writeDirs(w, entries); err != nil {
+		return res, err
+	}
+	for _, e := range rules {
+		if err := writeEntry(w, e); err != nil {
+			return res, err
+		}
+	}
+	var solidEntries []entry
+	if opts.Solid {
+		if err := writeSolid(w, entries); err != nil {
+			return res, err
+		}
+		solidEntries, entries = entries, nil
+	}
+	if dict != nil {
+		w.RegisterCompressor(fs.MethodDeflateDict, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriterDict(out, flate.DefaultCompression, dict)
+		})
+		dh := &zip.FileHeader{Name: fs.MetaDict, Method: zip.Store}
+		dh.SetModTime(mtimeDate)
+		if err := writeEntry(w, entry{header: dh, data: dict}); err != nil {
+			return res, err
+		}
+	}
+	for _, e := range entries {
+		if dict != nil {
+			e.header.Method = fs.MethodDeflateDict
+		}
+		if err := writeEntry(w, e); err != nil {
+			return res, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return res, err
+	}
+	res.ZipSize = buffer.Len()
+	if res.Files, err = fileStats(buffer.Bytes()); err != nil {
+		return res, err
+	}
+	for _, e := range solidEntries {
+		res.Files = append(res.Files, FileStats{
+			Path:   "/" + e.header.Name,
+			Size:   int64(len(e.data)),
+			Method: "solid",
+		})
+	}
+
+	var tags string
+	if opts.Tags != "" {
+		tags = "\n// +build " + opts.Tags + "\n"
+	}
+
+	var comment string
+	if opts.Comment != "" {
+		comment = "\n" + commentLines(opts.Comment)
+	}
+
+	// e.g.)
+	// assetNamespaceIdentify is "AbcDeF_G"
+	// when assetNamespace is "abc de f-g"
+	assetNamespace := opts.Namespace
+	assetNamespaceIdentify := toSymbolSafe(assetNamespace)
+
+	// then embed it as a quoted string
+	var qb bytes.Buffer
+	fmt.Fprintf(&qb, `%s. DO NOT EDIT.
+%s%s
+%s%s
+package %s
+
+import (
+	"github.com/rakyll/statik/fs"
+)
+
+`, GeneratedNotice, FingerprintPrefix, res.Fingerprint, tags, comment, opts.Package)
+	if !fs.IsDefaultNamespace(assetNamespace) {
+		fmt.Fprintf(&qb, `
+const %s = "%s" // static asset namespace
+`, assetNamespaceIdentify, assetNamespace)
+	}
+	if opts.Consts {
+		// Identifiers are prefixed by the namespace so that several
+		// namespaces can be generated into the same package.
+		var prefix string
+		if !fs.IsDefaultNamespace(assetNamespace) {
+			prefix = assetNamespaceIdentify
+		}
+		filesVar := prefix + "Files"
+		writePathConsts(&qb, filesVar, pathConsts(prefix, res.Paths, filesVar, assetNamespaceIdentify))
+	}
+	fmt.Fprint(&qb, `
+func init() {
+	data := "`)
+	n := qb.Len()
+	FprintZipData(&qb, buffer.Bytes())
+	res.LiteralSize = qb.Len() - n + len(`""`)
+	if fs.IsDefaultNamespace(assetNamespace) {
+		fmt.Fprint(&qb, `"
+		fs.Register(data)
+	}
+	`)
+
+	} else {
+		fmt.Fprintf(&qb, `"
+		fs.RegisterWithNamespace("%s", data)
+	}
+	`, assetNamespace)
+	}
+
+	_, err = qb.WriteTo(opts.Output)
+	return res, err
+}
+
+// fileStats returns the size of the entries of the zip data.
+func fileStats(zipData []byte) ([]FileStats, error) {
+	zr, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return nil, err
+	}
+	var stats []FileStats
+	for _, zf := range zr.File {
+		st := FileStats{
+			Path:       zf.Name,
+			Size:       int64(zf.UncompressedSize64),
+			Compressed: int64(zf.CompressedSize64),
+		}
+		switch zf.Method {
+		case zip.Store:
+			st.Method = "store"
+		case zip.Deflate:
+			st.Method = "deflate"
+		case fs.MethodDeflateDict:
+			st.Method = "deflate+dict"
+		}
+		if !strings.HasPrefix(zf.Name, fs.MetaDir) {
+			st.Path = "/" + zf.Name
+		}
+		stats = append(stats, st)
+	}
+	return stats, nil
+}
+
+// walkSource walks on the source directory and calls fn with the path
+// relative to the source directory, the file info and the contents of
+// every file to embed, that is every file that is not hidden, matches
+// the includes and does not match the excludes. With Options.GitMtime,
+// the modification time of the file info is the one taken from git.
+func walkSource(ctx context.Context, opts *Options, fn func(relPath string, fi os.FileInfo, b []byte) error) error {
+	var gitTimes map[string]time.Time
+	if opts.GitMtime {
+		var err error
+		if gitTimes, err = gitModTimes(ctx, opts.Src); err != nil {
+			return err
+		}
+	}
+	return filepath.Walk(opts.Src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Ignore directories and hidden files.
+		// No entry is needed for directories in a zip file.
+		// Each file is represented with a path, no directory
+		// entities are required to build the hierarchy.
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			// Skip excluded directories altogether.
+			if fi.IsDir() && path != opts.Src && len(opts.Exclude) > 0 {
+				if b, e := match(opts.Exclude, path); e != nil {
+					return e
+				} else if b {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		relPath, err := filepath.Rel(opts.Src, path)
+		if err != nil {
+			return err
+		}
+		// Entries under fs.MetaDir are reserved for metadata,
+		// and rule files are embedded as metadata.
+		if strings.HasPrefix(filepath.ToSlash(relPath), fs.MetaDir) || isRuleFile(relPath) {
+			return nil
+		}
+
+		if len(opts.Include) > 0 {
+			if b, e := match(opts.Include, path); e != nil {
+				return e
+			} else if !b {
+				return nil
+			}
+		}
+
+		if len(opts.Exclude) > 0 {
+			if b, e := match(opts.Exclude, path); e != nil {
+				return e
+			} else if b {
+				return nil
+			}
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if opts.GitMtime {
+			t, ok := gitTimes[relPath]
+			if !ok {
+				t = opts.modTime()
+			}
+			fi = modTimeInfo{FileInfo: fi, modTime: t}
+		}
+		return fn(relPath, fi, b)
+	})
+}
+
+// writeEntry writes e into the archive w.
+func writeEntry(w *zip.Writer, e entry) error {
+	f, err := w.CreateHeader(e.header)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(e.data)
+	return err
+}
+
+// Check if an array contains an item
+func contains(slice []string, item string) bool {
+	set := make(map[string]struct{}, len(slice))
+	for _, s := range slice {
+		set[s] = struct{}{}
+	}
+
+	_, ok := set[item]
+	return ok
+}
+
+// Match a path with some of inclusions
+func match(incs []string, path string) (bool, error) {
+	var err error
+	for _, inc := range incs {
+		matches, e := filepath.Glob(spath.Join(filepath.Dir(path), inc))
+
+		if e != nil {
+			err = e
+		}
+
+		if matches != nil && len(matches) != 0 && contains(matches, path) {
+			return true, nil
+		}
+	}
+
+	return false, err
+}
+
+// FprintZipData converts zip binary contents to a string literal.
+func FprintZipData(dest *bytes.Buffer, zipData []byte) {
+	for _, b := range zipData {
+		if b == '\n' {
+			dest.WriteString(`\n`)
+			continue
+		}
+		if b == '\\' {
+			dest.WriteString(`\\`)
+			continue
+		}
+		if b == '"' {
+			dest.WriteString(`\"`)
+			continue
+		}
+		if (b >= 32 && b <= 126) || b == '\t' {
+			dest.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(dest, "\\x%02x", b)
+	}
+}
+
+// comment lines prefixes each line in lines with "// ".
+func commentLines(lines string) string {
+	lines = "// " + strings.Replace(lines, "\n", "\n// ", -1)
+	return lines
+}
+
+// convert src to symbol safe string with upper camel case
+func toSymbolSafe(str string) string {
+	isBeforeRuneNoGeneralCase := false
+	replace := func(r rune) rune {
+		if unicode.IsLetter(r) {
+			if isBeforeRuneNoGeneralCase {
+				isBeforeRuneNoGeneralCase = true
+				return r
+			} else {
+				isBeforeRuneNoGeneralCase = true
+				return unicode.ToTitle(r)
+			}
+		} else if unicode.IsDigit(r) {
+			if isBeforeRuneNoGeneralCase {
+				isBeforeRuneNoGeneralCase = true
+				return r
+			} else {
+				isBeforeRuneNoGeneralCase = false
+				return -1
+			}
+		} else {
+			isBeforeRuneNoGeneralCase = false
+			return -1
+		}
+	}
+	return strings.TrimSpace(strings.Map(replace, str))
+}
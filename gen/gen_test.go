@@ -0,0 +1,705 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rakyll/statik/fs"
+)
+
+func TestToSymbolSafe(t *testing.T) {
+	testCase := [][]string{
+		{"abc", "Abc"},
+		{"_abc", "Abc"},
+		{"3abc", "Abc"},
+		{"abc3", "Abc3"},
+		{"/abc", "Abc"},
+		{"abc abc", "AbcAbc"},
+	}
+	for i, test := range testCase {
+		got := toSymbolSafe(test[0])
+		wont := test[1]
+		if got != wont {
+			t.Errorf("#%02d toSymbolSafe(%s) => %s != %s", i, test[0], got, wont)
+		}
+	}
+}
+
+// similarContent returns the i-th of a series of small JSON documents
+// sharing most of their content.
+func similarContent(i int) string {
+	return fmt.Sprintf(`{"id": %d, "type": "icon", "attributes": {"width": 24, "height": 24, "viewBox": "0 0 24 24", "fill": "none", "stroke": "currentColor"}, "name": "icon-%d"}`, i, i)
+}
+
+// mustSimilarTree creates a directory holding n similar files.
+func mustSimilarTree(t *testing.T, n int) string {
+	dir, err := ioutil.TempDir("", "statik")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("%02d.json", i))
+		if err := ioutil.WriteFile(name, []byte(similarContent(i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGenerateDict(t *testing.T) {
+	dir := mustSimilarTree(t, 20)
+	defer os.RemoveAll(dir)
+
+	res, _, data := mustGenerate(t, Options{Src: dir, Dict: true})
+	if res.Dict == nil || res.Dict.Size == 0 {
+		t.Fatalf("Generate() did not use a dictionary: %+v", res.Dict)
+	}
+	if res.Dict.WithDict >= res.Dict.Plain {
+		t.Errorf("dictionary grew compressed size from %d to %d", res.Dict.Plain, res.Dict.WithDict)
+	}
+
+	zr, err := zip.NewReader(strings.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, zf := range zr.File {
+		if strings.HasPrefix(zf.Name, fs.MetaDir) {
+			continue
+		}
+		if zf.Method != fs.MethodDeflateDict {
+			t.Errorf("%s method = %d; want %d", zf.Name, zf.Method, fs.MethodDeflateDict)
+		}
+	}
+
+	fs.RegisterWithNamespace("dict", data)
+	hfs, err := fs.NewWithNamespace("dict")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("/%02d.json", i)
+		b, err := fs.ReadFile(hfs, name)
+		if err != nil {
+			t.Fatalf("ReadFile(%v) = %v", name, err)
+		}
+		if want := similarContent(i); string(b) != want {
+			t.Errorf("ReadFile(%v) = %q; want %q", name, b, want)
+		}
+	}
+	if _, err := hfs.Open("/" + fs.MetaDict); err != os.ErrNotExist {
+		t.Errorf("Open(%v) = %v; want %v", fs.MetaDict, err, os.ErrNotExist)
+	}
+}
+
+func TestGenerateSolid(t *testing.T) {
+	_, _, data := mustGenerate(t, Options{Src: "../testdata/deep", Solid: true})
+
+	fs.RegisterWithNamespace("solid", data)
+	hfs, err := fs.NewWithNamespace("solid")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	for _, name := range []string{"/a", "/aa/bb/c"} {
+		b, err := fs.ReadFile(hfs, name)
+		if err != nil {
+			t.Fatalf("ReadFile(%v) = %v", name, err)
+		}
+		want, err := ioutil.ReadFile(filepath.Join("../testdata/deep", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, want) {
+			t.Errorf("ReadFile(%v) = %q; want %q", name, b, want)
+		}
+		f, err := hfs.Open(name)
+		if err != nil {
+			t.Fatalf("Open(%v) = %v", name, err)
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatalf("Stat(%v) = %v", name, err)
+		}
+		wantFi, err := os.Stat(filepath.Join("../testdata/deep", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := fi.ModTime(), wantFi.ModTime().Truncate(time.Second); !got.Equal(want) {
+			t.Errorf("ModTime(%v) = %v; want %v", name, got, want)
+		}
+		if got, want := fi.Size(), wantFi.Size(); got != want {
+			t.Errorf("Size(%v) = %v; want %v", name, got, want)
+		}
+	}
+	var names []string
+	if err := fs.Walk(hfs, "/", func(path string, fi os.FileInfo, err error) error {
+		names = append(names, path)
+		return err
+	}); err != nil {
+		t.Fatalf("Walk() = %v", err)
+	}
+	if got, want := strings.Join(names, ","), "/,/a,/aa,/aa/bb,/aa/bb/c"; got != want {
+		t.Errorf("Walk() visited %v; want %v", got, want)
+	}
+}
+
+func TestGenerateTransform(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("transform commands in this test require a POSIX shell")
+	}
+	cacheDir, err := ioutil.TempDir("", "statik-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	dir := mustTree(t, map[string]string{
+		"a.css":     "body{}",
+		"b.css":     "p{}",
+		"sub/c.css": "a{}",
+		"d.html":    "<p>",
+	})
+	defer os.RemoveAll(dir)
+	transforms := []Transform{
+		{Pattern: "*.css", Command: "tr a-z A-Z"},
+		{Pattern: "*.css", Command: "cat; printf !"},
+	}
+	_, _, data := mustGenerate(t, Options{Src: dir, Transforms: transforms, TransformCache: cacheDir})
+	fs.RegisterWithNamespace("transform", data)
+	hfs, err := fs.NewWithNamespace("transform")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	for name, want := range map[string]string{
+		"/a.css":     "BODY{}!",
+		"/b.css":     "P{}!",
+		"/sub/c.css": "A{}!",
+		"/d.html":    "<p>",
+	} {
+		b, err := fs.ReadFile(hfs, name)
+		if err != nil {
+			t.Fatalf("ReadFile(%v) = %v", name, err)
+		}
+		if string(b) != want {
+			t.Errorf("ReadFile(%v) = %q; want %q", name, b, want)
+		}
+	}
+	cached, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 6 {
+		t.Errorf("got %d cached results; want 6", len(cached))
+	}
+
+	// Files with the same contents are transformed separately, since
+	// commands may depend on their path.
+	dir = mustTree(t, map[string]string{"a.txt": "aaa", "b.txt": "aaa"})
+	defer os.RemoveAll(dir)
+	transforms = []Transform{{Pattern: "*.txt", Command: "echo $STATIK_FILE"}}
+	for i := 0; i < 2; i++ {
+		// The second run reads the results from the cache.
+		_, _, data = mustGenerate(t, Options{Src: dir, Transforms: transforms, TransformCache: cacheDir})
+		fs.RegisterWithNamespace("transform", data)
+		hfs, err = fs.NewUncached("transform")
+		if err != nil {
+			t.Fatalf("NewUncached() = %v", err)
+		}
+		for _, name := range []string{"a.txt", "b.txt"} {
+			if b, err := fs.ReadFile(hfs, "/"+name); err != nil || string(b) != name+"\n" {
+				t.Errorf("run %d: ReadFile(/%s) = %q, %v; want %q", i, name, b, err, name+"\n")
+			}
+		}
+	}
+
+	// Failures are reported for every file.
+	_, err = Generate(context.Background(), Options{
+		Src:        "../testdata/deep",
+		Transforms: []Transform{{Pattern: "*", Command: "echo oops >&2; exit 3"}},
+		Output:     ioutil.Discard,
+	})
+	terr, ok := err.(TransformError)
+	if !ok {
+		t.Fatalf("Generate() = %v; want a TransformError", err)
+	}
+	if len(terr) != 2 {
+		t.Errorf("got %d failures; want 2: %v", len(terr), terr)
+	}
+	for _, msg := range terr {
+		if !strings.Contains(msg, "oops") {
+			t.Errorf("failure %q does not contain the command output", msg)
+		}
+	}
+}
+
+func TestPathConsts(t *testing.T) {
+	got := pathConsts("", []string{"/a.txt", "/a_txt", "/files", "/404.html", "/日本"}, "Files")
+	want := []pathConst{
+		{name: "ATxt", path: "/a.txt"},
+		{name: "ATxt2", path: "/a_txt"},
+		{name: "Files2", path: "/files"},
+		{name: "Html", path: "/404.html"},
+		{name: "File日本", path: "/日本"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pathConsts() = %v; want %v", got, want)
+	}
+}
+
+func TestGenerateConsts(t *testing.T) {
+	_, src, _ := mustGenerate(t, Options{Src: "../testdata/deep", Consts: true})
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+	for _, want := range []string{
+		"\tA = \"/a\"\n",
+		"\tAaBbC = \"/aa/bb/c\"\n",
+		"var Files = []string{\n\tA,\n\tAaBbC,\n}\n",
+	} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("generated code does not contain %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateStats(t *testing.T) {
+	res, src, data := mustGenerate(t, Options{Src: "../testdata/image", NoCompress: true})
+	if res.ZipSize != len(data) {
+		t.Errorf("ZipSize = %d; want %d", res.ZipSize, len(data))
+	}
+	lit := src[bytes.Index(src, []byte("data := "))+len("data := "):]
+	lit = lit[:bytes.IndexByte(lit, '\n')]
+	if res.LiteralSize != len(lit) {
+		t.Errorf("LiteralSize = %d; want %d", res.LiteralSize, len(lit))
+	}
+	want := []FileStats{
+		{Path: fs.MetaTypes, Size: 22, Compressed: 29, Method: "deflate"},
+		{Path: fs.MetaDirs, Size: 16, Compressed: 23, Method: "deflate"},
+		{Path: "/pixel.gif", Size: 42, Compressed: 42, Method: "store"},
+	}
+	if !reflect.DeepEqual(res.Files, want) {
+		t.Errorf("Files = %+v; want %+v", res.Files, want)
+	}
+
+	res, _, _ = mustGenerate(t, Options{Src: "../testdata/deep", Solid: true})
+	methods := make(map[string]string)
+	for _, st := range res.Files {
+		methods[st.Path] = st.Method
+	}
+	for path, want := range map[string]string{
+		"/a":         "solid",
+		"/aa/bb/c":   "solid",
+		fs.MetaSolid: "deflate",
+		fs.MetaIndex: "deflate",
+	} {
+		if methods[path] != want {
+			t.Errorf("method of %s = %q; want %q", path, methods[path], want)
+		}
+	}
+}
+
+func TestGenerateBudgets(t *testing.T) {
+	const size = 100
+	content := strings.Repeat("x", size)
+	dir := mustTree(t, map[string]string{
+		"a.js":       content,
+		"b.js":       content,
+		"c.js":       content,
+		"app.js.map": "{}",
+		"empty.map":  "",
+	})
+	defer os.RemoveAll(dir)
+	opts := Options{
+		Src:          dir,
+		MaxFileSize:  size,
+		MaxTotalSize: 3 * size,
+		Budgets:      []Budget{{Pattern: "*.map", MaxSize: 0}},
+		Output:       ioutil.Discard,
+	}
+	_, err := Generate(context.Background(), opts)
+	errs, ok := err.(BudgetError)
+	if !ok {
+		t.Fatalf("Generate() = %v; want a BudgetError", err)
+	}
+	// A budget of 0 also forbids empty files.
+	want := BudgetError{
+		`/app.js.map: files matching "*.map" are not allowed`,
+		`/empty.map: files matching "*.map" are not allowed`,
+		fmt.Sprintf("total size of %s exceeds the maximum of %s", FormatSize(3*size+2), FormatSize(3*size)),
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("Generate() = %q; want %q", errs, want)
+	}
+
+	opts.WarnBudgets = true
+	res, err := Generate(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Generate() = %v", err)
+	}
+	if !reflect.DeepEqual(res.OverBudget, want) {
+		t.Errorf("OverBudget = %q; want %q", res.OverBudget, want)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{
+		"0":     0,
+		"512":   512,
+		"10B":   10,
+		"2k":    2048,
+		"1.5MB": 3 << 19,
+		"1G":    1 << 30,
+	} {
+		if got, err := ParseSize(s); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "MB", "-1", "1TB"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q) = nil error; want an error", s)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statik-write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "statik", "statik.go")
+	generated := []byte(GeneratedNotice + ". DO NOT EDIT.\n\npackage statik\n")
+
+	if err := WriteFile(dest, generated, false); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+	fi, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0644 {
+		t.Errorf("mode of %s = %v; want %v", dest, fi.Mode().Perm(), os.FileMode(0644))
+	}
+	// Generated files are overwritten.
+	if err := WriteFile(dest, generated, false); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+
+	handwritten := []byte("package statik\n")
+	if err := ioutil.WriteFile(dest, handwritten, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(dest, generated, false); err == nil {
+		t.Errorf("WriteFile() overwrote a file not generated by statik")
+	}
+	if b, _ := ioutil.ReadFile(dest); !bytes.Equal(b, handwritten) {
+		t.Errorf("WriteFile() changed a file not generated by statik to %q", b)
+	}
+	if err := WriteFile(dest, generated, true); err != nil {
+		t.Fatalf("WriteFile() with force = %v", err)
+	}
+	if b, _ := ioutil.ReadFile(dest); !bytes.Equal(b, generated) {
+		t.Errorf("WriteFile() with force wrote %q; want %q", b, generated)
+	}
+
+	files, err := ioutil.ReadDir(filepath.Dir(dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("WriteFile() left temporary files: %d files in %s", len(files), filepath.Dir(dest))
+	}
+}
+
+func TestGenerateReproducible(t *testing.T) {
+	// Two trees with the same files, as checked out by differently
+	// configured systems: other permissions and modification times.
+	var srcs [2][]byte
+	for i, tree := range []struct {
+		mode, execMode os.FileMode
+		mtime          time.Time
+	}{
+		{0600, 0700, time.Date(2019, time.March, 1, 10, 0, 0, 0, time.UTC)},
+		{0664, 0775, time.Date(2021, time.June, 2, 12, 30, 0, 0, time.Local)},
+	} {
+		dir := mustTree(t, map[string]string{
+			"index.html":   "<!doctype html>",
+			"css/site.css": "body{}",
+			"bin/run.sh":   "#!/bin/sh\n",
+		})
+		defer os.RemoveAll(dir)
+		err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			mode := tree.mode
+			if strings.HasSuffix(path, ".sh") {
+				mode = tree.execMode
+			}
+			if err := os.Chmod(path, mode); err != nil {
+				return err
+			}
+			return os.Chtimes(path, tree.mtime, tree.mtime)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, srcs[i], _ = mustGenerate(t, Options{Src: dir, Reproducible: true})
+	}
+	if !bytes.Equal(srcs[0], srcs[1]) {
+		t.Errorf("generated code differs between trees:\n%s\n%s", srcs[0], srcs[1])
+	}
+
+	sourceDate := time.Unix(1600000000, 0)
+	_, _, data := mustGenerate(t, Options{Src: "../testdata/deep", Reproducible: true, SourceDate: sourceDate})
+	fs.RegisterWithNamespace("reproducible", data)
+	hfs, err := fs.NewWithNamespace("reproducible")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	f, err := hfs.Open("/a")
+	if err != nil {
+		t.Fatalf("Open(/a) = %v", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(sourceDate) || fi.Mode() != 0644 {
+		t.Errorf("Stat(/a) = %v %v; want %v %v", fi.ModTime(), fi.Mode(), sourceDate, os.FileMode(0644))
+	}
+}
+
+func TestGenerateGitMtime(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file names in this test are not valid on Windows")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	// Names starting with "@" or with a newline must not be confused
+	// with commit times.
+	dir := mustTree(t, map[string]string{
+		"public/a.txt":   "a",
+		"public/b.txt":   "b",
+		"public/c.txt":   "c",
+		"public/@2x.png": "png",
+		"public/\nd.txt": "d",
+	})
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "public")
+	git := func(date string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=statik", "-c", "user.email=statik@example.com"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("", "init", "-q")
+	git("1500000000 +0000", "add", "public/a.txt", "public/b.txt", "public/@2x.png")
+	git("1500000000 +0000", "commit", "-q", "-m", "first")
+	git("1550000000 +0000", "add", "public/\nd.txt")
+	git("1550000000 +0000", "commit", "-q", "-m", "newline")
+	if err := ioutil.WriteFile(filepath.Join(src, "b.txt"), []byte("B"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("1600000000 +0000", "commit", "-q", "-a", "-m", "second")
+
+	sourceDate := time.Unix(1400000000, 0)
+	_, _, data := mustGenerate(t, Options{Src: src, GitMtime: true, Reproducible: true, SourceDate: sourceDate})
+	fs.RegisterWithNamespace("git", data)
+	hfs, err := fs.NewWithNamespace("git")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	for name, want := range map[string]time.Time{
+		"/a.txt":   time.Unix(1500000000, 0),
+		"/b.txt":   time.Unix(1600000000, 0),
+		"/@2x.png": time.Unix(1500000000, 0),
+		"/\nd.txt": time.Unix(1550000000, 0),
+		"/c.txt":   sourceDate, // untracked
+	} {
+		f, err := hfs.Open(name)
+		if err != nil {
+			t.Fatalf("Open(%s) = %v", name, err)
+		}
+		fi, err := f.Stat()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.ModTime().Equal(want) {
+			t.Errorf("ModTime of %s = %v; want %v", name, fi.ModTime(), want)
+		}
+	}
+}
+
+func TestGenerateTypes(t *testing.T) {
+	dir := mustTree(t, map[string]string{
+		"app.wasm":         "\x00asm",
+		"site.webmanifest": "{}",
+		"page.HTML":        "<p>",
+		"data.bin":         "%PDF-1.4",
+		"custom.tmpl":      "{{.}}",
+		"api/items.json":   "[]",
+	})
+	defer os.RemoveAll(dir)
+	_, _, data := mustGenerate(t, Options{
+		Src:       dir,
+		Solid:     true,
+		MIMETypes: map[string]string{".tmpl": "text/x-go-template", ".json": "application/vnd.api+json"},
+	})
+	fs.RegisterWithNamespace("types", data)
+	hfs, err := fs.NewWithNamespace("types")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	for name, want := range map[string]string{
+		"/app.wasm":         "application/wasm",
+		"/site.webmanifest": "application/manifest+json",
+		"/page.HTML":        "text/html; charset=utf-8",
+		"/data.bin":         "application/pdf",
+		"/custom.tmpl":      "text/x-go-template",
+		"/api/items.json":   "application/vnd.api+json",
+	} {
+		if got := fs.ContentType(hfs, name); got != want {
+			t.Errorf("ContentType(%s) = %q; want %q", name, got, want)
+		}
+	}
+}
+
+func TestGenerateHeaders(t *testing.T) {
+	dir := mustTree(t, map[string]string{
+		"index.html": "<!doctype html>",
+		HeadersFile:  "/*\n  X-Frame-Options: DENY\n",
+	})
+	defer os.RemoveAll(dir)
+	headers := filepath.Join(dir, HeadersFile)
+	res, _, data := mustGenerate(t, Options{Src: dir, Include: []string{"*"}})
+	if !reflect.DeepEqual(res.Paths, []string{"/index.html"}) {
+		t.Errorf("Paths = %v; want only /index.html", res.Paths)
+	}
+	fp, err := Fingerprint(context.Background(), Options{Src: dir, Include: []string{"*"}})
+	if err != nil || fp != res.Fingerprint {
+		t.Errorf("Fingerprint() = %q, %v; want %q", fp, err, res.Fingerprint)
+	}
+	zr, err := zip.NewReader(strings.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, zf := range zr.File {
+		found = found || zf.Name == fs.MetaHeaders
+	}
+	if !found {
+		t.Errorf("%s is not embedded as %s", HeadersFile, fs.MetaHeaders)
+	}
+
+	if err := ioutil.WriteFile(headers, []byte("/*\n  X-Frame-Options DENY\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Generate(context.Background(), Options{Src: dir, Output: ioutil.Discard})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Generate() with an invalid %s = %v; want an error on line 2", HeadersFile, err)
+	}
+}
+
+func TestGenerateRedirects(t *testing.T) {
+	dir := mustTree(t, map[string]string{
+		"index.html":  "<!doctype html>",
+		"404.html":    "not found",
+		RedirectsFile: "/old /index.html\n/*  /404.html  404\n",
+	})
+	defer os.RemoveAll(dir)
+	redirects := filepath.Join(dir, RedirectsFile)
+	res, _, _ := mustGenerate(t, Options{Src: dir, Include: []string{"*"}})
+	if !reflect.DeepEqual(res.Paths, []string{"/404.html", "/index.html"}) {
+		t.Errorf("Paths = %v; want only /404.html and /index.html", res.Paths)
+	}
+	found := false
+	for _, st := range res.Files {
+		found = found || st.Path == fs.MetaRedirects
+	}
+	if !found {
+		t.Errorf("%s is not embedded as %s", RedirectsFile, fs.MetaRedirects)
+	}
+
+	if err := ioutil.WriteFile(redirects, []byte("/old /new\n/blog/:slug /posts/:id\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Generate(context.Background(), Options{Src: dir, Output: ioutil.Discard})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Generate() with an invalid %s = %v; want an error on line 2", RedirectsFile, err)
+	}
+}
+
+// mustTree creates a directory holding the given files, by
+// slash-separated path.
+func mustTree(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "statik")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// mustGenerate generates code with opts and returns the result, the
+// generated code and the zip data it registers.
+func mustGenerate(t *testing.T, opts Options) (Result, []byte, string) {
+	var src bytes.Buffer
+	opts.Output = &src
+	res, err := Generate(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Generate() = %v", err)
+	}
+	const prefix = "data := "
+	i := bytes.Index(src.Bytes(), []byte(prefix))
+	if i < 0 {
+		t.Fatalf("no zip data in generated code:\n%s", src.Bytes())
+	}
+	lit := src.Bytes()[i+len(prefix):]
+	lit = lit[:bytes.IndexByte(lit, '\n')]
+	data, err := strconv.Unquote(string(lit))
+	if err != nil {
+		t.Fatalf("strconv.Unquote() = %v", err)
+	}
+	return res, src.Bytes(), data
+}
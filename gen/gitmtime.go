@@ -0,0 +1,81 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitModTimes returns the time of the last commit changing each file
+// under dir, by path relative to dir, from the history of the git
+// repository containing dir.
+func gitModTimes(ctx context.Context, dir string) (map[string]time.Time, error) {
+	// Commits are listed newest first, each as the commit time followed
+	// by the files it changed, all terminated by NUL bytes. The format
+	// starts with an extra NUL byte: file names are never empty, so an
+	// empty field always precedes a commit time.
+	cmd := exec.CommandContext(ctx, "git", "log", "-z", "--format=%x00%ct",
+		"--name-only", "--no-renames", "--relative", "--", ".")
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git log in %s: %s: %s", dir, err, strings.TrimSpace(stderr.String()))
+	}
+	times := make(map[string]time.Time)
+	var t time.Time
+	commit, first := false, false
+	for _, field := range strings.Split(stdout.String(), "\x00") {
+		switch {
+		case field == "":
+			commit = true
+		case commit:
+			sec, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("git log in %s: unexpected commit time %q", dir, field)
+			}
+			t = time.Unix(sec, 0).UTC()
+			commit, first = false, true
+		default:
+			if first {
+				// The file list is separated from the commit time by a
+				// newline.
+				field = strings.TrimPrefix(field, "\n")
+				first = false
+			}
+			name := filepath.FromSlash(field)
+			if _, ok := times[name]; !ok {
+				times[name] = t
+			}
+		}
+	}
+	return times, nil
+}
+
+// modTimeInfo overrides the modification time of a file.
+type modTimeInfo struct {
+	os.FileInfo
+	modTime time.Time
+}
+
+func (fi modTimeInfo) ModTime() time.Time { return fi.modTime }
@@ -0,0 +1,98 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	spath "path"
+	"strconv"
+	"strings"
+
+	"github.com/rakyll/statik/fs"
+)
+
+// mimeTypes maps file extensions to content types. Unlike the tables
+// of package mime, it does not depend on the system generating the code.
+var mimeTypes = map[string]string{
+	".apng":        "image/apng",
+	".avif":        "image/avif",
+	".bmp":         "image/bmp",
+	".css":         "text/css; charset=utf-8",
+	".csv":         "text/csv; charset=utf-8",
+	".eot":         "application/vnd.ms-fontobject",
+	".gif":         "image/gif",
+	".gz":          "application/gzip",
+	".htm":         "text/html; charset=utf-8",
+	".html":        "text/html; charset=utf-8",
+	".ico":         "image/x-icon",
+	".jpeg":        "image/jpeg",
+	".jpg":         "image/jpeg",
+	".js":          "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".jsonld":      "application/ld+json",
+	".map":         "application/json",
+	".md":          "text/markdown; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".mp3":         "audio/mpeg",
+	".mp4":         "video/mp4",
+	".oga":         "audio/ogg",
+	".ogg":         "audio/ogg",
+	".ogv":         "video/ogg",
+	".otf":         "font/otf",
+	".pdf":         "application/pdf",
+	".png":         "image/png",
+	".svg":         "image/svg+xml",
+	".tar":         "application/x-tar",
+	".ttf":         "font/ttf",
+	".txt":         "text/plain; charset=utf-8",
+	".wasm":        "application/wasm",
+	".wav":         "audio/wav",
+	".webm":        "video/webm",
+	".webmanifest": "application/manifest+json",
+	".webp":        "image/webp",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".xml":         "text/xml; charset=utf-8",
+	".zip":         "application/zip",
+}
+
+// contentType returns the content type of the file name with contents b:
+// the type of its extension in overrides or in mimeTypes, or else the
+// type sniffed from its contents.
+func contentType(name string, b []byte, overrides map[string]string) string {
+	ext := strings.ToLower(spath.Ext(name))
+	if t, ok := overrides[ext]; ok {
+		return t
+	}
+	if t, ok := mimeTypes[ext]; ok {
+		return t
+	}
+	return http.DetectContentType(b)
+}
+
+// writeTypes writes the content type of every entry into w.
+// See the statik/fs package for the format.
+func writeTypes(w *zip.Writer, entries []entry, overrides map[string]string) error {
+	var types bytes.Buffer
+	for _, e := range entries {
+		fmt.Fprintf(&types, "%s\t%s\n", strconv.Quote(e.header.Name), contentType(e.header.Name, e.data, overrides))
+	}
+	h := &zip.FileHeader{Name: fs.MetaTypes, Method: zip.Deflate}
+	h.SetModTime(mtimeDate)
+	return writeEntry(w, entry{header: h, data: types.Bytes()})
+}
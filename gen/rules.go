@@ -0,0 +1,86 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/rakyll/statik/fs"
+)
+
+// HeadersFile is the name of the file of the source directory holding
+// response header rules, in the format of Netlify _headers files.
+// It is embedded as metadata rather than as a file; see fs.ParseHeaders.
+const HeadersFile = "_headers"
+
+// RedirectsFile is the name of the file of the source directory holding
+// redirect rules, in the format of Netlify _redirects files.
+// It is embedded as metadata rather than as a file; see fs.ParseRedirects.
+const RedirectsFile = "_redirects"
+
+// ruleFile is a file of the source directory embedded as metadata.
+type ruleFile struct {
+	name  string // name in the source directory
+	meta  string // name of the archive entry
+	parse func([]byte) error
+}
+
+var ruleFiles = []ruleFile{
+	{HeadersFile, fs.MetaHeaders, func(b []byte) error {
+		_, err := fs.ParseHeaders(b)
+		return err
+	}},
+	{RedirectsFile, fs.MetaRedirects, func(b []byte) error {
+		_, err := fs.ParseRedirects(b)
+		return err
+	}},
+}
+
+// readRules reads and validates the rule files of the source directory,
+// and returns the metadata entries holding them.
+func readRules(opts *Options) ([]entry, error) {
+	var rules []entry
+	for _, rf := range ruleFiles {
+		b, err := ioutil.ReadFile(filepath.Join(opts.Src, rf.name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := rf.parse(b); err != nil {
+			return nil, fmt.Errorf("%s: %s", filepath.Join(opts.Src, rf.name), err)
+		}
+		h := &zip.FileHeader{Name: rf.meta, Method: zip.Deflate}
+		h.SetModTime(mtimeDate)
+		rules = append(rules, entry{header: h, data: b})
+	}
+	return rules, nil
+}
+
+// isRuleFile reports whether relPath is a rule file, which is not
+// embedded as a file.
+func isRuleFile(relPath string) bool {
+	for _, rf := range ruleFiles {
+		if relPath == rf.name {
+			return true
+		}
+	}
+	return false
+}
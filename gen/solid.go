@@ -0,0 +1,52 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/rakyll/statik/fs"
+)
+
+// writeSolid writes the entries into w as a solid archive: the contents
+// of all entries are concatenated and compressed as a single stream,
+// next to an index of their offsets in the stream.
+// See the statik/fs package for the format of the index.
+func writeSolid(w *zip.Writer, entries []entry) error {
+	var index, solid bytes.Buffer
+	for _, e := range entries {
+		fmt.Fprintf(&index, "%d %d %d %d %s\n",
+			solid.Len(), len(e.data), uint32(e.header.Mode()),
+			e.header.Modified.Unix(), strconv.Quote(e.header.Name))
+		solid.Write(e.data)
+	}
+	for _, m := range []struct {
+		name string
+		data []byte
+	}{
+		{fs.MetaIndex, index.Bytes()},
+		{fs.MetaSolid, solid.Bytes()},
+	} {
+		h := &zip.FileHeader{Name: m.name, Method: zip.Deflate}
+		h.SetModTime(mtimeDate)
+		if err := writeEntry(w, entry{header: h, data: m.data}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
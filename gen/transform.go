@@ -0,0 +1,138 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Transform is a command that rewrites the contents of the files
+// matching Pattern, a wildcard matched against file names. The command
+// reads the original contents from its standard input and writes the
+// transformed ones to its standard output. It is run by the shell,
+// with the STATIK_FILE environment variable set to the path of the
+// file relative to the source directory.
+type Transform struct {
+	Pattern string
+	Command string
+}
+
+// TransformError reports the files that failed to be transformed.
+type TransformError []string
+
+func (e TransformError) Error() string {
+	return fmt.Sprintf("transform failed for %d file(s):\n  %s", len(e), strings.Join(e, "\n  "))
+}
+
+// applyTransforms runs the transforms matching the file name of relPath,
+// in order, on b and returns the result. Results are cached in cacheDir
+// by the hash of the command, relPath and the input, unless cacheDir is
+// empty.
+func applyTransforms(ctx context.Context, transforms []Transform, cacheDir, relPath string, b []byte) ([]byte, error) {
+	name := filepath.Base(relPath)
+	for _, t := range transforms {
+		if ok, _ := filepath.Match(t.Pattern, name); !ok {
+			continue
+		}
+		var err error
+		if b, err = runTransform(ctx, t, cacheDir, relPath, b); err != nil {
+			return nil, fmt.Errorf("%s: %q: %s", filepath.ToSlash(relPath), t.Command, err)
+		}
+	}
+	return b, nil
+}
+
+func runTransform(ctx context.Context, t Transform, cacheDir, relPath string, in []byte) ([]byte, error) {
+	var cached string
+	if cacheDir != "" {
+		// Commands may depend on STATIK_FILE, so the path is part of
+		// the key.
+		h := sha256.New()
+		fmt.Fprintf(h, "%s\x00%s\x00", t.Command, filepath.ToSlash(relPath))
+		h.Write(in)
+		cached = filepath.Join(cacheDir, hex.EncodeToString(h.Sum(nil)))
+		if b, err := ioutil.ReadFile(cached); err == nil {
+			return b, nil
+		}
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", t.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", t.Command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Let commands know which file they are transforming.
+	cmd.Env = append(os.Environ(), "STATIK_FILE="+filepath.ToSlash(relPath))
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	if cached != "" {
+		// Failing to cache only costs running the command again next time.
+		writeCache(cached, stdout.Bytes())
+	}
+	return stdout.Bytes(), nil
+}
+
+// writeCache atomically writes b to the cache file name.
+func writeCache(name string, b []byte) error {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, ".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// DefaultTransformCache returns the directory transform results are
+// cached in by default, or an empty string if there is none.
+func DefaultTransformCache() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "statik", "transform")
+}
@@ -0,0 +1,102 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GeneratedNotice starts the first line of the generated code.
+const GeneratedNotice = "// Code generated by statik"
+
+// WriteFile atomically writes the generated code src to the file
+// dest, creating its directory if needed. The code is written to a
+// temporary file in the same directory, synced and renamed to dest, so
+// that dest is never left partially written.
+//
+// An existing dest is only overwritten if it was generated by statik,
+// that is if it starts with GeneratedNotice, or if force is set.
+func WriteFile(dest string, src []byte, force bool) error {
+	if !force {
+		generated, err := isGenerated(dest)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil && !generated {
+			return fmt.Errorf("file %q already exists and was not generated by statik; use -f to overwrite", dest)
+		}
+	}
+
+	dir := filepath.Dir(dest)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(dest)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(src)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		// Temporary files are only readable by their owner.
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), dest)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// isGenerated reports whether the file at filename starts with
+// GeneratedNotice.
+func isGenerated(filename string) (bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && line == "" {
+		// An empty file is not worth keeping.
+		return true, nil
+	}
+	return strings.HasPrefix(line, GeneratedNotice), nil
+}
+
+// syncDir makes the rename of a file in dir durable. Errors are
+// ignored as directories cannot be synced on every system.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,101 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultPkg is the name of the generated package if neither -p
+// nor the Go files next to -o give one.
+const defaultPkg = "statik"
+
+// stdoutOutput is the value of -o writing the generated code
+// to the standard output.
+const stdoutOutput = "-"
+
+// output returns the path of the generated file described by the flags
+// and the name of its package. Without -p, the package name is the one
+// of the Go files in the directory of -o.
+func output() (dest, pkg string, err error) {
+	pkg = *flagPkg
+	switch *flagOutput {
+	case "":
+		if pkg == "" {
+			pkg = defaultPkg
+		}
+		return path.Join(*flagDest, pkg, nameSourceFile), pkg, nil
+	case stdoutOutput:
+		if pkg == "" {
+			pkg = defaultPkg
+		}
+		return stdoutOutput, pkg, nil
+	}
+	dest = *flagOutput
+	if pkg == "" {
+		if pkg, err = packageName(dest); err != nil {
+			return "", "", err
+		}
+	}
+	return dest, pkg, nil
+}
+
+// packageName returns the package name of the Go files next to
+// filename, or defaultPkg if there are none. Test files and filename
+// itself are ignored.
+func packageName(filename string) (string, error) {
+	dir := filepath.Dir(filename)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		// The directory is created with the generated file.
+		return defaultPkg, nil
+	}
+	names := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, fi := range files {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") ||
+			name == filepath.Base(filename) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		names[f.Name.Name] = true
+	}
+	switch len(names) {
+	case 0:
+		return defaultPkg, nil
+	case 1:
+		for name := range names {
+			return name, nil
+		}
+	}
+	var found []string
+	for name := range names {
+		found = append(found, name)
+	}
+	sort.Strings(found)
+	return "", fmt.Errorf("cannot infer the package name, %s has Go files of packages %s; use -p",
+		dir, strings.Join(found, " and "))
+}
@@ -0,0 +1,130 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rakyll/statik/fs"
+	"github.com/rakyll/statik/gen"
+)
+
+// reportFlag is the format of the report given with -report, which
+// can also be used as a boolean flag to print a table.
+type reportFlag string
+
+func (f *reportFlag) String() string { return string(*f) }
+
+func (f *reportFlag) Set(value string) error {
+	switch value {
+	case "true", "table":
+		*f = "table"
+	case "false", "":
+		*f = ""
+	case "json":
+		*f = "json"
+	default:
+		return fmt.Errorf("unknown report format %q; want table or json", value)
+	}
+	return nil
+}
+
+func (f *reportFlag) IsBoolFlag() bool { return true }
+
+// report describes the size of the generated code. Its files and
+// totals cover the embedded files and not the metadata entries, except
+// for the compressed size of the solid stream holding the files of
+// solid archives, which only the zip size accounts for.
+type report struct {
+	Files           []reportFile `json:"files"`
+	TotalSize       int64        `json:"total_size"`
+	TotalCompressed int64        `json:"total_compressed"`
+	ZipSize         int          `json:"zip_size"`
+	LiteralSize     int          `json:"literal_size"`
+}
+
+type reportFile struct {
+	Path       string  `json:"path"`
+	Size       int64   `json:"size"`
+	Compressed int64   `json:"compressed"`
+	Ratio      float64 `json:"ratio"`
+	Method     string  `json:"method"`
+}
+
+// newReport returns the report of res, with the largest
+// contributors to the archive size first.
+func newReport(res gen.Result) *report {
+	r := &report{ZipSize: res.ZipSize, LiteralSize: res.LiteralSize}
+	for _, st := range res.Files {
+		if st.Path == fs.MetaSolid {
+			// The compressed size of the files of solid archives.
+			r.TotalCompressed += st.Compressed
+		}
+		if strings.HasPrefix(st.Path, fs.MetaDir) {
+			continue
+		}
+		f := reportFile{Path: st.Path, Size: st.Size, Compressed: st.Compressed, Method: st.Method}
+		if st.Size > 0 && st.Method != "solid" {
+			f.Ratio = float64(st.Compressed) / float64(st.Size)
+		}
+		r.Files = append(r.Files, f)
+		r.TotalSize += st.Size
+		r.TotalCompressed += st.Compressed
+	}
+	// Files of solid archives have no compressed size of their own,
+	// order them by size.
+	weight := func(f reportFile) int64 {
+		if f.Method == "solid" {
+			return f.Size
+		}
+		return f.Compressed
+	}
+	sort.SliceStable(r.Files, func(i, j int) bool {
+		return weight(r.Files[i]) > weight(r.Files[j])
+	})
+	return r
+}
+
+func (r *report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *report) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "SIZE\tCOMPRESSED\tRATIO\tMETHOD\t\tPATH")
+	for _, f := range r.Files {
+		compressed, ratio := "-", "-"
+		if f.Method != "solid" {
+			compressed = fmt.Sprint(f.Compressed)
+			if f.Size > 0 {
+				ratio = fmt.Sprintf("%.1f%%", f.Ratio*100)
+			}
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t\t%s\n", f.Size, compressed, ratio, f.Method, f.Path)
+	}
+	fmt.Fprintf(tw, "%d\t%d\t\t\t\tTOTAL\n", r.TotalSize, r.TotalCompressed)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "zip data: %d bytes, string literal: %d bytes\n", r.ZipSize, r.LiteralSize)
+	return err
+}
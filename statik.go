@@ -17,27 +17,23 @@
 package main
 
 import (
-	"archive/zip"
 	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path"
-	spath "path"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
-	"unicode"
 
-	"github.com/rakyll/statik/fs"
+	"github.com/rakyll/statik/gen"
 )
 
 const nameSourceFile = "statik.go"
 
-var namePackage string
-
 var (
 	flagSrc        = flag.String("src", path.Join(".", "public"), "")
 	flagDest       = flag.String("dest", ".", "")
@@ -45,34 +41,130 @@ var (
 	flagNoCompress = flag.Bool("Z", false, "")
 	flagForce      = flag.Bool("f", false, "")
 	flagTags       = flag.String("tags", "", "")
-	flagPkg        = flag.String("p", "statik", "")
+	flagPkg        = flag.String("p", "", "")
 	flagNamespace  = flag.String("ns", "default", "")
 	flagPkgCmt     = flag.String("c", "", "")
 	flagInclude    = flag.String("include", "*.*", "")
+	flagExclude    = flag.String("exclude", "", "")
+	flagDict       = flag.Bool("dict", false, "")
+	flagSolid      = flag.Bool("solid", false, "")
+	flagConsts     = flag.Bool("consts", false, "")
+	flagOutput     = flag.String("o", "", "")
+
+	flagReproducible = flag.Bool("reproducible", false, "")
+	flagMtime        = flag.String("mtime", "", "")
+
+	flagReport reportFlag
+	flagMIME   mimeFlag
+
+	flagMaxFileSize  sizeFlag
+	flagMaxTotalSize sizeFlag
+	flagBudgets      budgetFlag
+	flagWarnBudgets  = flag.Bool("warn-budgets", false, "")
+
+	flagTransforms     transformFlag
+	flagTransformCache = flag.String("transform-cache", gen.DefaultTransformCache(), "")
+
+	flagConfig = flag.String("config", "", "")
+	flagCheck  = flag.Bool("check", false, "")
+
+	flagWatch         = flag.Bool("watch", false, "")
+	flagWatchInterval = flag.Duration("watch-interval", 500*time.Millisecond, "")
 )
 
+func init() {
+	flag.Var(&flagTransforms, "transform", "")
+	flag.Var(&flagReport, "report", "")
+	flag.Var(&flagMIME, "mime", "")
+	flag.Var(&flagMaxFileSize, "max-file-size", "")
+	flag.Var(&flagMaxTotalSize, "max-total-size", "")
+	flag.Var(&flagBudgets, "budget", "")
+}
+
 const helpText = `statik [options]
 
 Options:
 -src     The source directory of the assets, "public" by default.
 -dest    The destination directory of the generated package, "." by default.
+-o       The generated file, overriding -dest, such as "assets/assets_gen.go".
+         "-" writes the generated code to the standard output.
 
 -ns      The namespace where assets will exist, "default" by default.
--f       Override destination if it already exists, false by default.
+-f       Overwrite the destination even if it was not generated
+         by statik, false by default.
 -include Wildcard to filter files to include, "*.*" by default.
+-exclude Wildcard to filter files and directories to exclude,
+         none by default.
 -m       Ignore modification times for deterministic output, false by default.
--Z       Do not use compression, false by default.
-
--p       Name of the generated package, "statik" by default.
+-reproducible  Generate the same code on every system: ignore modification
+         times like -m and normalize modes to 0644 or 0755, false by default.
+         With -m or -reproducible, files are dated SOURCE_DATE_EPOCH if set.
+-mtime   Where modification times come from: "file", the default, or "git"
+         for the time of the last commit changing each file. Files never
+         committed are dated like with -m.
+-Z       Do not use compression, false by default. Uncompressed files
+         are served from the generated data without being copied.
+-dict    Compress with a preset dictionary selected from the assets,
+         false by default. Useful for many small similar files.
+-solid   Compress all files as a single stream, false by default.
+
+-transform       Pattern and command, as pattern=command, transforming the
+                 files matching the pattern. The command reads the file from
+                 its standard input and writes the result to its standard
+                 output. Can be repeated; matching commands run in order.
+-transform-cache Directory caching transform results, a "statik" directory
+                 in the user cache directory by default. Empty to disable.
+
+-mime    Extension and content type, as .ext=type, recorded for the files
+         with the extension instead of the built-in type. Can be repeated.
+         Files with an unknown extension get the type sniffed from their
+         contents. fs.FileServer serves files with the recorded types.
+
+-p       Name of the generated package. By default, the package of the
+         Go files next to the -o file, or "statik".
 -tags    Build tags for the generated package.
 -c       Godoc for the generated package.
+-consts  Generate a constant for the path of every file and a Files
+         slice listing them, false by default. Identifiers are prefixed
+         with the namespace unless it is the default one.
+
+-max-file-size   Fail if a file is larger than the given size, such as
+                 "512KB" or "10MB", after transforms. Unlimited by default.
+-max-total-size  Fail if the files are larger than the given size in total,
+                 after transforms. Unlimited by default.
+-budget          Pattern and size, as pattern=size, failing if a file
+                 matching the pattern is larger than the size. A size of 0
+                 forbids matching files. Can be repeated.
+-warn-budgets    Print the files exceeding a size limit instead of failing,
+                 false by default.
+
+-report  Print the size of every embedded file, compressed and not,
+         and the total size of the generated data, largest first.
+         Use -report=json for a JSON report.
+
+-check   Check that the generated package is up to date instead of
+         writing it. Exits with an error listing the added (+), removed (-)
+         and changed (~) assets if it is not. Modification times are only
+         compared with -m.
+
+-watch   Keep running and generate the package again whenever the
+         source directory changes, false by default.
+-watch-interval  How often to poll the source directory, "500ms" by default.
+
+-config  JSON file describing packages to generate. Flags override
+         the values of the file.
 
 -help    Prints this text.
 
+A _headers file at the root of the source directory sets response headers
+per path, and a _redirects file redirects or rewrites requests, in the
+format of Netlify _headers and _redirects files. They are validated and
+embedded as metadata, and applied by fs.FileServer.
+
 Examples:
 
-Generates a statik package from ./assets directory. Overrides
-if there is already an existing package.
+Generates a statik package from ./assets directory. Overwrites
+the destination even if it was not generated by statik.
 
    $ statik -src=assets -f
 
@@ -80,262 +172,217 @@ Generates a statik package only with the ".js" files
 from the ./public directory.
 
    $ statik -include=*.js
-`
 
-// mtimeDate holds the arbitrary mtime that we assign to files when
-// flagNoMtime is set.
-var mtimeDate = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+Generates a statik package with minified ".js" files.
+
+   $ statik -transform='*.js=terser -c -m'
+
+Generates the assets of the "web" namespace into an existing package.
+
+   $ statik -src=web -ns=web -o=internal/server/web_assets.go
+
+Fails if a file is larger than 1MB or if a source map is embedded.
+
+   $ statik -max-file-size=1MB -budget='*.map=0'
+
+Fails if the statik package is out of date, for example in CI.
+
+   $ statik -m -check
+
+Generates the packages described in statik.json, such as:
+
+   {
+     "targets": [
+       {"src": "public", "include": ["*.js", "*.css"], "no_mtime": true},
+       {"src": "templates", "namespace": "tmpl", "compression": "solid"}
+     ]
+   }
+
+   $ statik -config=statik.json
+`
 
 func main() {
 	flag.Usage = help
 	flag.Parse()
 
-	namePackage = *flagPkg
-
-	file, err := generateSource(*flagSrc, *flagInclude)
-	if err != nil {
-		exitWithError(err)
-	}
-
-	destDir := path.Join(*flagDest, namePackage)
-	err = os.MkdirAll(destDir, 0755)
-	if err != nil {
-		exitWithError(err)
+	var err error
+	switch {
+	case *flagWatch && (*flagConfig != "" || *flagCheck):
+		err = errors.New("-watch cannot be used with -config or -check")
+	case *flagOutput == stdoutOutput && (*flagWatch || *flagCheck || *flagConfig != ""):
+		err = errors.New("-o - cannot be used with -watch, -check or -config")
+	case *flagWatch:
+		err = watch(*flagWatchInterval, nil)
+	case *flagConfig != "":
+		err = runConfig(*flagConfig, explicitFlags())
+	default:
+		err = run()
 	}
-
-	err = rename(file.Name(), path.Join(destDir, nameSourceFile))
 	if err != nil {
 		exitWithError(err)
 	}
 }
 
-// rename tries to os.Rename, but fall backs to copying from src
-// to dest and unlink the source if os.Rename fails.
-func rename(src, dest string) error {
-	// Try to rename generated source.
-	if err := os.Rename(src, dest); err == nil {
-		return nil
+// options returns the generation options described by the flags.
+func options() gen.Options {
+	opts := gen.Options{
+		Src:            *flagSrc,
+		Package:        *flagPkg,
+		Namespace:      *flagNamespace,
+		Comment:        *flagPkgCmt,
+		Tags:           *flagTags,
+		NoMtime:        *flagNoMtime,
+		Reproducible:   *flagReproducible,
+		NoCompress:     *flagNoCompress,
+		Dict:           *flagDict,
+		Solid:          *flagSolid,
+		Consts:         *flagConsts,
+		Transforms:     flagTransforms,
+		TransformCache: *flagTransformCache,
+		MaxFileSize:    int64(flagMaxFileSize),
+		MaxTotalSize:   int64(flagMaxTotalSize),
+		MIMETypes:      flagMIME,
+		Budgets:        flagBudgets,
+		WarnBudgets:    *flagWarnBudgets,
 	}
-	// If the rename failed (might do so due to temporary file residing on a
-	// different device), try to copy byte by byte.
-	rc, err := os.Open(src)
-	if err != nil {
-		return err
+	if *flagInclude != "" {
+		opts.Include = strings.Split(*flagInclude, ",")
 	}
-	defer func() {
-		rc.Close()
-		os.Remove(src) // ignore the error, source is in tmp.
-	}()
-
-	if _, err = os.Stat(dest); !os.IsNotExist(err) {
-		if *flagForce {
-			if err = os.Remove(dest); err != nil {
-				return fmt.Errorf("file %q could not be deleted", dest)
-			}
-		} else {
-			return fmt.Errorf("file %q already exists; use -f to overwrite", dest)
-		}
+	if *flagExclude != "" {
+		opts.Exclude = strings.Split(*flagExclude, ",")
 	}
+	return opts
+}
 
-	wc, err := os.Create(dest)
+// run generates the package described by the flags.
+func run() error {
+	ctx := context.Background()
+	opts := options()
+	dest, pkg, err := output()
 	if err != nil {
 		return err
 	}
-	defer wc.Close()
-
-	if _, err = io.Copy(wc, rc); err != nil {
-		// Delete remains of failed copy attempt.
-		os.Remove(dest)
-	}
-	return err
-}
-
-// Check if an array contains an item
-func contains(slice []string, item string) bool {
-	set := make(map[string]struct{}, len(slice))
-	for _, s := range slice {
-		set[s] = struct{}{}
-	}
-
-	_, ok := set[item]
-	return ok
-}
-
-// Match a path with some of inclusions
-func match(incs []string, path string) (bool, error) {
-	var err error
-	for _, inc := range incs {
-		matches, e := filepath.Glob(spath.Join(filepath.Dir(path), inc))
-
-		if e != nil {
-			err = e
-		}
-
-		if matches != nil && len(matches) != 0 && contains(matches, path) {
-			return true, nil
-		}
+	opts.Package = pkg
+	if opts.SourceDate, err = sourceDate(); err != nil {
+		return err
 	}
-
-	return false, err
-}
-
-// Walks on the source path and generates source code
-// that contains source directory's contents as zip contents.
-// Generates source registers generated zip contents data to
-// be read by the statik/fs HTTP file system.
-func generateSource(srcPath string, includes string) (file *os.File, err error) {
-	var (
-		buffer    bytes.Buffer
-		zipWriter io.Writer
-	)
-
-	zipWriter = &buffer
-	f, err := ioutil.TempFile("", namePackage)
-	if err != nil {
-		return
+	switch *flagMtime {
+	case "", "file":
+	case "git":
+		opts.GitMtime = true
+	default:
+		return fmt.Errorf("invalid -mtime %q; want file or git", *flagMtime)
 	}
 
-	zipWriter = io.MultiWriter(zipWriter, f)
-	defer f.Close()
-
-	w := zip.NewWriter(zipWriter)
-	if err = filepath.Walk(srcPath, func(path string, fi os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		// Ignore directories and hidden files.
-		// No entry is needed for directories in a zip file.
-		// Each file is represented with a path, no directory
-		// entities are required to build the hierarchy.
-		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
-			return nil
-		}
-		relPath, err := filepath.Rel(srcPath, path)
+	if dest == stdoutOutput {
+		// Messages go to the standard error not to mix with the code.
+		opts.Output = os.Stdout
+		res, err := gen.Generate(ctx, opts)
 		if err != nil {
 			return err
 		}
-		b, err := ioutil.ReadFile(path)
-		if err != nil {
-			return err
-		}
-
-		incs := strings.Split(includes, ",")
-
-		if b, e := match(incs, path); e != nil {
-			return err
-		} else if !b {
-			return nil
-		}
+		printDictStats(os.Stderr, res.Dict)
+		printOverBudget(os.Stderr, res.OverBudget)
+		return printReport(os.Stderr, res)
+	}
 
-		fHeader, err := zip.FileInfoHeader(fi)
+	if *flagCheck {
+		// Nothing is written to disk with -check, not even to the cache.
+		opts.TransformCache = ""
+		var src bytes.Buffer
+		opts.Output = &src
+		res, err := gen.Generate(ctx, opts)
 		if err != nil {
 			return err
 		}
-		if *flagNoMtime {
-			// Always use the same modification time so that
-			// the output is deterministic with respect to the file contents.
-			// Do NOT use fHeader.Modified as it only works on go >= 1.10
-			fHeader.SetModTime(mtimeDate)
-		}
-		fHeader.Name = filepath.ToSlash(relPath)
-		if !*flagNoCompress {
-			fHeader.Method = zip.Deflate
-		}
-		f, err := w.CreateHeader(fHeader)
-		if err != nil {
-			return err
-		}
-		_, err = f.Write(b)
+		printOverBudget(os.Stdout, res.OverBudget)
+		return check(dest, src.Bytes())
+	}
+
+	// Leave the generated file untouched if its inputs did not change.
+	fp, err := gen.Fingerprint(ctx, opts)
+	if err != nil {
 		return err
-	}); err != nil {
-		return
 	}
-	if err = w.Close(); err != nil {
-		return
+	upToDate := fp == readFingerprint(dest)
+	if upToDate && flagReport == "" {
+		return nil
 	}
 
-	var tags string
-	if *flagTags != "" {
-		tags = "\n// +build " + *flagTags + "\n"
+	var src bytes.Buffer
+	opts.Output = &src
+	res, err := gen.Generate(ctx, opts)
+	if err != nil {
+		return err
 	}
-
-	var comment string
-	if *flagPkgCmt != "" {
-		comment = "\n" + commentLines(*flagPkgCmt)
+	printDictStats(os.Stdout, res.Dict)
+	printOverBudget(os.Stdout, res.OverBudget)
+	if err := printReport(os.Stdout, res); err != nil {
+		return err
+	}
+	if upToDate {
+		return nil
 	}
 
-	// e.g.)
-	// assetNamespaceIdentify is "AbcDeF_G"
-	// when assetNamespace is "abc de f-g"
-	assetNamespace := *flagNamespace
-	assetNamespaceIdentify := toSymbolSafe(assetNamespace)
-
-	// then embed it as a quoted string
-	var qb bytes.Buffer
-	fmt.Fprintf(&qb, `// Code generated by statik. DO NOT EDIT.
-%s%s
-package %s
-
-import (
-	"github.com/rakyll/statik/fs"
-)
+	return gen.WriteFile(dest, src.Bytes(), *flagForce)
+}
 
-`, tags, comment, namePackage)
-	if !fs.IsDefaultNamespace(assetNamespace) {
-		fmt.Fprintf(&qb, `
-const %s = "%s" // static asset namespace
-`, assetNamespaceIdentify, assetNamespace)
+// printReport prints the report of res in the format given with -report.
+func printReport(w io.Writer, res gen.Result) error {
+	switch flagReport {
+	case "table":
+		return newReport(res).writeTable(w)
+	case "json":
+		return newReport(res).writeJSON(w)
 	}
-	fmt.Fprint(&qb, `
-func init() {
-	data := "`)
-	FprintZipData(&qb, buffer.Bytes())
-	if fs.IsDefaultNamespace(assetNamespace) {
-		fmt.Fprint(&qb, `"
-		fs.Register(data)
-	}
-	`)
+	return nil
+}
 
-	} else {
-		fmt.Fprintf(&qb, `"
-		fs.RegisterWithNamespace("%s", data)
+// sourceDate returns the time given by the SOURCE_DATE_EPOCH environment
+// variable, or the zero time if it is not set.
+// See https://reproducible-builds.org/specs/source-date-epoch/.
+func sourceDate() (time.Time, error) {
+	v := os.Getenv("SOURCE_DATE_EPOCH")
+	if v == "" {
+		return time.Time{}, nil
 	}
-	`, assetNamespace)
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", v)
 	}
+	return time.Unix(sec, 0).UTC(), nil
+}
 
-	if err = ioutil.WriteFile(f.Name(), qb.Bytes(), 0644); err != nil {
-		return
+// readFingerprint returns the fingerprint recorded in the generated
+// file, or an empty string if there is none.
+func readFingerprint(filename string) string {
+	f, err := os.Open(filename)
+	if err != nil {
+		return ""
 	}
-	return f, nil
+	defer f.Close()
+	return gen.ReadFingerprint(f)
 }
 
-// FprintZipData converts zip binary contents to a string literal.
-func FprintZipData(dest *bytes.Buffer, zipData []byte) {
-	for _, b := range zipData {
-		if b == '\n' {
-			dest.WriteString(`\n`)
-			continue
-		}
-		if b == '\\' {
-			dest.WriteString(`\\`)
-			continue
-		}
-		if b == '"' {
-			dest.WriteString(`\"`)
-			continue
-		}
-		if (b >= 32 && b <= 126) || b == '\t' {
-			dest.WriteByte(b)
-			continue
-		}
-		fmt.Fprintf(dest, "\\x%02x", b)
+// printDictStats prints how much the preset dictionary saved.
+func printDictStats(w io.Writer, stats *gen.DictStats) {
+	switch {
+	case stats == nil:
+	case stats.Plain == 0:
+		fmt.Fprintln(w, "statik: no common content found for a preset dictionary; using deflate")
+	case stats.Size == 0:
+		fmt.Fprintf(w, "statik: preset dictionary would grow compressed size from %d to %d bytes; using deflate\n",
+			stats.Plain, stats.WithDict)
+	default:
+		fmt.Fprintf(w, "statik: preset dictionary of %d bytes reduced compressed size from %d to %d bytes (saved %d bytes)\n",
+			stats.Size, stats.Plain, stats.WithDict, stats.Plain-stats.WithDict)
 	}
 }
 
-// comment lines prefixes each line in lines with "// ".
-func commentLines(lines string) string {
-	lines = "// " + strings.Replace(lines, "\n", "\n// ", -1)
-	return lines
+func printOverBudget(w io.Writer, errs gen.BudgetError) {
+	if len(errs) > 0 {
+		fmt.Fprintf(w, "statik: warning: %s\n", errs)
+	}
 }
 
 // Prints out the error message and exists with a non-success signal.
@@ -344,35 +391,7 @@ func exitWithError(err error) {
 	os.Exit(1)
 }
 
-// convert src to symbol safe string with upper camel case
-func toSymbolSafe(str string) string {
-	isBeforeRuneNoGeneralCase := false
-	replace := func(r rune) rune {
-		if unicode.IsLetter(r) {
-			if isBeforeRuneNoGeneralCase {
-				isBeforeRuneNoGeneralCase = true
-				return r
-			} else {
-				isBeforeRuneNoGeneralCase = true
-				return unicode.ToTitle(r)
-			}
-		} else if unicode.IsDigit(r) {
-			if isBeforeRuneNoGeneralCase {
-				isBeforeRuneNoGeneralCase = true
-				return r
-			} else {
-				isBeforeRuneNoGeneralCase = false
-				return -1
-			}
-		} else {
-			isBeforeRuneNoGeneralCase = false
-			return -1
-		}
-	}
-	return strings.TrimSpace(strings.Map(replace, str))
-}
-
 func help() {
-	fmt.Println(helpText)
+	fmt.Print(helpText)
 	os.Exit(1)
 }
@@ -1,21 +1,429 @@
 package main
 
-import "testing"
-
-func TestToSymbolSafe(t *testing.T) {
-	testCase := [][]string{
-		{"abc", "Abc"},
-		{"_abc", "Abc"},
-		{"3abc", "Abc"},
-		{"abc3", "Abc3"},
-		{"/abc", "Abc"},
-		{"abc abc", "AbcAbc"},
-	}
-	for i, test := range testCase {
-		got := toSymbolSafe(test[0])
-		wont := test[1]
-		if got != wont {
-			t.Errorf("#%02d toSymbolSafe(%s) => %s != %s", i, test[0], got, wont)
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rakyll/statik/fs"
+	"github.com/rakyll/statik/gen"
+)
+
+// mustGenerate returns the code generated with opts.
+func mustGenerate(t *testing.T, opts gen.Options) []byte {
+	var src bytes.Buffer
+	opts.Output = &src
+	if _, err := gen.Generate(context.Background(), opts); err != nil {
+		t.Fatalf("Generate() = %v", err)
+	}
+	return src.Bytes()
+}
+
+// mustTree creates a directory holding the given files, by
+// slash-separated path.
+func mustTree(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "statik")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// saveFlags returns a function restoring the values of the flags
+// targets set. It assigns the variables of the flags rather than
+// calling flag.Set, which would mark the flags as given on the
+// command line.
+func saveFlags() func() {
+	strs := []*string{flagSrc, flagDest, flagOutput, flagPkg, flagNamespace,
+		flagPkgCmt, flagInclude, flagExclude, flagTags, flagMtime}
+	bools := []*bool{flagForce, flagNoMtime, flagReproducible, flagNoCompress,
+		flagDict, flagSolid, flagConsts, flagWarnBudgets}
+	strVals := make([]string, len(strs))
+	for i, p := range strs {
+		strVals[i] = *p
+	}
+	boolVals := make([]bool, len(bools))
+	for i, p := range bools {
+		boolVals[i] = *p
+	}
+	transforms, mime, budgets := flagTransforms, flagMIME, flagBudgets
+	maxFileSize, maxTotalSize := flagMaxFileSize, flagMaxTotalSize
+	return func() {
+		for i, p := range strs {
+			*p = strVals[i]
+		}
+		for i, p := range bools {
+			*p = boolVals[i]
+		}
+		flagTransforms, flagMIME, flagBudgets = transforms, mime, budgets
+		flagMaxFileSize, flagMaxTotalSize = maxFileSize, maxTotalSize
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statik-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := fmt.Sprintf(`{
+  "targets": [
+    {"src": %q, "dest": ".", "package": "deep", "no_mtime": true},
+    {"src": %q, "dest": ".", "package": "index", "namespace": "web", "include": ["*.html"], "exclude": ["sub_dir"], "compression": "solid"}
+  ]
+}`, filepath.Join(src, "deep"), filepath.Join(src, "index"))
+	configFile := filepath.Join(dir, "statik.json")
+	if err := ioutil.WriteFile(configFile, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Flags given on the command line override the config.
+	defer saveFlags()()
+	*flagTags = "dev"
+	if err := runConfig(configFile, map[string]bool{"tags": true}); err != nil {
+		t.Fatalf("runConfig() = %v", err)
+	}
+
+	deep := mustReadFile(t, filepath.Join(dir, "deep", nameSourceFile))
+	if !bytes.Contains(deep, []byte("package deep")) {
+		t.Errorf("deep package has a wrong package name:\n%s", deep)
+	}
+	if !bytes.Contains(deep, []byte("// +build dev")) {
+		t.Errorf("deep package is missing the build tags given with -tags:\n%s", deep)
+	}
+	index := mustReadFile(t, filepath.Join(dir, "index", nameSourceFile))
+	if !bytes.Contains(index, []byte(`fs.RegisterWithNamespace("web", data)`)) {
+		t.Errorf("index package is not registered in the web namespace:\n%s", index)
+	}
+	_, data, err := splitSource(index)
+	if err != nil {
+		t.Fatalf("splitSource() = %v", err)
+	}
+	fs.RegisterWithNamespace("web", data)
+	hfs, err := fs.NewWithNamespace("web")
+	if err != nil {
+		t.Fatalf("NewWithNamespace() = %v", err)
+	}
+	if _, err := hfs.Open("/index.html"); err != nil {
+		t.Errorf("Open(/index.html) = %v", err)
+	}
+	if _, err := hfs.Open("/sub_dir/index.html"); err != os.ErrNotExist {
+		t.Errorf("Open(/sub_dir/index.html) = %v; want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestReadConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statik-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, config := range []string{
+		`{"targets": []}`,
+		`{"targets": [{"compression": "lzma"}]}`,
+		`{"targets": [{"source": "public"}]}`,
+	} {
+		configFile := filepath.Join(dir, "statik.json")
+		if err := ioutil.WriteFile(configFile, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readConfig(configFile); err == nil {
+			t.Errorf("readConfig(%s) = nil; want an error", config)
 		}
 	}
 }
+
+func mustReadFile(t *testing.T, filename string) []byte {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCheck(t *testing.T) {
+	dir := mustTree(t, map[string]string{
+		"index.html": "<!doctype html>",
+		"app.js":     "app()",
+		"site.css":   "body{}",
+	})
+	defer os.RemoveAll(dir)
+	*flagNoMtime = true
+	defer func() { *flagNoMtime = false }()
+
+	include := []string{"*.html", "*.js", "*.css"}
+	src := mustGenerate(t, gen.Options{Src: dir, Include: include, NoMtime: true})
+	dest := filepath.Join(dir, nameSourceFile)
+	if err := check(dest, src); err != errStale {
+		t.Errorf("check() on a missing file = %v; want %v", err, errStale)
+	}
+	if err := ioutil.WriteFile(dest, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := check(dest, src); err != nil {
+		t.Errorf("check() = %v; want nil", err)
+	}
+
+	// Add, remove and change assets.
+	if err := os.Remove(filepath.Join(dir, "index.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte("app(1)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "new.js"), []byte("init()"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newSrc := mustGenerate(t, gen.Options{Src: dir, Include: include, NoMtime: true})
+	if err := check(dest, newSrc); err != errStale {
+		t.Errorf("check() = %v; want %v", err, errStale)
+	}
+	_, oldData, err := splitSource(src)
+	if err != nil {
+		t.Fatalf("splitSource() = %v", err)
+	}
+	_, newData, err := splitSource(newSrc)
+	if err != nil {
+		t.Fatalf("splitSource() = %v", err)
+	}
+	d, err := diffAssets(oldData, newData, true)
+	if err != nil {
+		t.Fatalf("diffAssets() = %v", err)
+	}
+	want := &assetDiff{
+		added:   []string{"/new.js"},
+		removed: []string{"/index.html"},
+		changed: []string{"/app.js"},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("diffAssets() = %+v; want %+v", d, want)
+	}
+}
+
+func TestRunSkipsUnchanged(t *testing.T) {
+	dir := mustTree(t, map[string]string{"index.html": "<!doctype html>", "app.js": "app()"})
+	defer os.RemoveAll(dir)
+	dest, err := ioutil.TempDir("", "statik-dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	*flagSrc, *flagDest = dir, dest
+	defer func() {
+		*flagSrc, *flagDest = flag.Lookup("src").DefValue, flag.Lookup("dest").DefValue
+	}()
+
+	if err := run(); err != nil {
+		t.Fatalf("run() = %v", err)
+	}
+	generated := filepath.Join(dest, "statik", nameSourceFile)
+	if readFingerprint(generated) == "" {
+		t.Fatalf("%s has no fingerprint", generated)
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(generated, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(); err != nil {
+		t.Fatalf("run() = %v", err)
+	}
+	fi, err := os.Stat(generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(past) {
+		t.Errorf("%s was rewritten although its inputs did not change", generated)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte("app(1)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(); err != nil {
+		t.Fatalf("run() = %v", err)
+	}
+	if fi, err = os.Stat(generated); err != nil {
+		t.Fatal(err)
+	}
+	if fi.ModTime().Equal(past) {
+		t.Errorf("%s was not rewritten although its inputs changed", generated)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := mustTree(t, map[string]string{"index.html": "<!doctype html>"})
+	defer os.RemoveAll(dir)
+	dest, err := ioutil.TempDir("", "statik-dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	*flagSrc, *flagDest = dir, dest
+	defer func() {
+		*flagSrc, *flagDest = flag.Lookup("src").DefValue, flag.Lookup("dest").DefValue
+	}()
+
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- watch(10*time.Millisecond, stop) }()
+	defer func() {
+		close(stop)
+		if err := <-done; err != nil {
+			t.Errorf("watch() = %v", err)
+		}
+	}()
+
+	generated := filepath.Join(dest, "statik", nameSourceFile)
+	waitForAsset := func(name, want string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			assets, _ := generatedAssets(generated)
+			if a, ok := assets[name]; ok && a.data == want {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("%s was not generated with %s = %q", generated, name, want)
+	}
+	waitForAsset("/index.html", "<!doctype html>")
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte("app()"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitForAsset("/app.js", "app()")
+}
+
+func TestReport(t *testing.T) {
+	r := newReport(gen.Result{
+		Files: []gen.FileStats{
+			{Path: "/small.txt", Size: 10, Compressed: 12, Method: "deflate"},
+			{Path: "/big.png", Size: 1000, Compressed: 1000, Method: "store"},
+			{Path: "/solid.js", Size: 500, Method: "solid"},
+			{Path: fs.MetaSolid, Size: 500, Compressed: 200, Method: "deflate"},
+			{Path: fs.MetaTypes, Size: 30, Compressed: 35, Method: "deflate"},
+		},
+		ZipSize:     1200,
+		LiteralSize: 2400,
+	})
+	var paths []string
+	for _, f := range r.Files {
+		paths = append(paths, f.Path)
+	}
+	// Metadata entries are left out.
+	if want := []string{"/big.png", "/solid.js", "/small.txt"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("report order = %v; want %v", paths, want)
+	}
+	// Files of solid archives are counted once, metadata is not counted.
+	if r.TotalSize != 1510 || r.TotalCompressed != 1212 {
+		t.Errorf("totals = %d, %d; want 1510, 1212", r.TotalSize, r.TotalCompressed)
+	}
+
+	var buf bytes.Buffer
+	if err := r.writeTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"120.0%  deflate  /small.txt", "-       -    solid  /solid.js", "TOTAL", "zip data: 1200 bytes"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("table does not contain %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := r.writeJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var got report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal() = %v", err)
+	}
+	if !reflect.DeepEqual(&got, r) {
+		t.Errorf("JSON report = %+v; want %+v", got, r)
+	}
+}
+
+func TestOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statik-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, src := range map[string]string{
+		"server.go":      "// Package web serves the site.\npackage web\n",
+		"server_test.go": "package web_test\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer saveFlags()()
+
+	src := mustTree(t, map[string]string{"index.html": "<!doctype html>"})
+	defer os.RemoveAll(src)
+	dest := filepath.Join(dir, "web_assets.go")
+	*flagSrc, *flagOutput = src, dest
+	if err := run(); err != nil {
+		t.Fatalf("run() = %v", err)
+	}
+	if b := mustReadFile(t, dest); !bytes.Contains(b, []byte("\npackage web\n")) {
+		t.Errorf("package name was not inferred from server.go:\n%s", b)
+	}
+	// Generated files do not prevent the inference.
+	*flagOutput = filepath.Join(dir, "more_assets.go")
+	if _, pkg, err := output(); err != nil || pkg != "web" {
+		t.Errorf("output() = %q, %v; want web", pkg, err)
+	}
+	*flagPkg = "assets"
+	if _, pkg, err := output(); err != nil || pkg != "assets" {
+		t.Errorf("output() with -p = %q, %v; want assets", pkg, err)
+	}
+
+	*flagPkg = ""
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := output(); err == nil {
+		t.Errorf("output() with two packages = nil error; want an error")
+	}
+	*flagOutput = filepath.Join(dir, "new", "assets.go")
+	if _, pkg, err := output(); err != nil || pkg != defaultPkg {
+		t.Errorf("output() in a new directory = %q, %v; want %s", pkg, err, defaultPkg)
+	}
+}
+
+func TestSourceDate(t *testing.T) {
+	defer os.Unsetenv("SOURCE_DATE_EPOCH")
+	for v, want := range map[string]time.Time{
+		"":           {},
+		"1600000000": time.Unix(1600000000, 0),
+	} {
+		os.Setenv("SOURCE_DATE_EPOCH", v)
+		if got, err := sourceDate(); err != nil || !got.Equal(want) {
+			t.Errorf("sourceDate() with SOURCE_DATE_EPOCH=%q = %v, %v; want %v", v, got, err, want)
+		}
+	}
+	os.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if _, err := sourceDate(); err == nil {
+		t.Errorf("sourceDate() with an invalid SOURCE_DATE_EPOCH = nil error; want an error")
+	}
+}
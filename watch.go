@@ -0,0 +1,136 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileState is the part of a file's info that changes when it is edited.
+type fileState struct {
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// snapshot returns the state of every file and directory under root.
+func snapshot(root string) (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// Files may disappear while walking; the next poll sees it.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		files[path] = fileState{size: fi.Size(), mode: fi.Mode(), modTime: fi.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+func sameSnapshot(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v.size != w.size || v.mode != w.mode || !v.modTime.Equal(w.modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// watch generates the package described by the flags, then polls the
+// source directory every interval and generates the package again once
+// changes have settled, that is when the source directory did not change
+// during a whole interval. It prints the assets that changed after each
+// generation. watch returns when stop is closed.
+func watch(interval time.Duration, stop <-chan struct{}) error {
+	dest, _, err := output()
+	if err != nil {
+		return err
+	}
+	rebuild := func() {
+		// If the previous file cannot be read, all assets are reported as added.
+		old, _ := generatedAssets(dest)
+		if err := run(); err != nil {
+			fmt.Printf("statik: %s\n", err)
+			return
+		}
+		assets, err := generatedAssets(dest)
+		if err != nil {
+			fmt.Printf("statik: %s\n", err)
+			return
+		}
+		d := compareAssets(old, assets, false)
+		fmt.Printf("statik: generated %s (%d added, %d removed, %d changed)\n",
+			dest, len(d.added), len(d.removed), len(d.changed))
+		d.print()
+	}
+
+	last, err := snapshot(*flagSrc)
+	if err != nil {
+		return err
+	}
+	rebuild()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	pending := false
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+		cur, err := snapshot(*flagSrc)
+		if err != nil {
+			fmt.Printf("statik: %s\n", err)
+			continue
+		}
+		if !sameSnapshot(last, cur) {
+			// Wait for a burst of changes to end.
+			last, pending = cur, true
+			continue
+		}
+		if pending {
+			pending = false
+			rebuild()
+		}
+	}
+}
+
+// generatedAssets returns the assets of the generated file at filename,
+// or no assets if it does not exist.
+func generatedAssets(filename string) (map[string]asset, error) {
+	src, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, data, err := splitSource(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return readAssets(data)
+}